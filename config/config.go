@@ -4,10 +4,15 @@
 package config
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/nuklai/nuklaivm/consts"
 )
 
@@ -15,7 +20,13 @@ type Config struct {
 	HTTPHost string
 	HTTPPort int
 
-	NuklaiRPC string
+	NuklaiRPC     string
+	FinalityDepth uint64 // blocks
+
+	// BlockArchiveURL serves the blocks missed while the feed was
+	// disconnected, see manager.ArchiveFetcher. Missed blocks are recorded as
+	// gaps until it is set.
+	BlockArchiveURL string
 
 	Recipient     string
 	recipientAddr codec.Address
@@ -26,7 +37,15 @@ type Config struct {
 	MessagesPerEpoch       int
 	TargetDurationPerEpoch int64 // seconds
 
-	AdminToken string
+	// Admin requests must be signed by one of AdminPublicKeys. Requests
+	// carrying AdminToken are only accepted if AdminTokenEnabled is set.
+	AdminPublicKeys   []ed25519.PublicKey
+	AdminToken        string
+	AdminTokenEnabled bool
+
+	// Storage backend: "postgres", "sqlite" or "memory"
+	DatabaseBackend string
+	SQLitePath      string
 
 	// PostgreSQL configuration
 	PostgresHost     string
@@ -48,6 +67,34 @@ func (c *Config) RecipientAddress() (codec.Address, error) {
 	return addr, err
 }
 
+// defaultAdminTokens are the placeholder admin tokens that were shipped as
+// defaults, which must never be used.
+var defaultAdminTokens = []string{"ADMIN_TOKEN", "YOUR_ADMIN_TOKEN"}
+
+var ErrDefaultAdminToken = errors.New("ADMIN_TOKEN is set to a default value")
+
+// ErrInvalidEpochDuration is returned when TARGET_DURATION_PER_EPOCH is not
+// positive, which would make every fee epoch end as soon as it starts.
+var ErrInvalidEpochDuration = errors.New("TARGET_DURATION_PER_EPOCH must be positive")
+
+// parsePublicKeys parses a comma-separated list of hex-encoded ed25519
+// public keys.
+func parsePublicKeys(raw string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(field, "0x"))
+		if err != nil || len(b) != ed25519.PublicKeyLen {
+			return nil, fmt.Errorf("invalid ed25519 public key %q", field)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	return keys, nil
+}
+
 func GetEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -85,6 +132,31 @@ func LoadConfigFromEnv() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if targetDurationPerEpoch <= 0 {
+		return nil, fmt.Errorf("%w: got %d", ErrInvalidEpochDuration, targetDurationPerEpoch)
+	}
+
+	finalityDepth, err := strconv.ParseUint(GetEnv("FINALITY_DEPTH", "3"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	adminPublicKeys, err := parsePublicKeys(GetEnv("ADMIN_PUBLIC_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	adminToken := GetEnv("ADMIN_TOKEN", "")
+	for _, token := range defaultAdminTokens {
+		if adminToken == token {
+			return nil, ErrDefaultAdminToken
+		}
+	}
+
+	adminTokenEnabled, err := strconv.ParseBool(GetEnv("ADMIN_TOKEN_ENABLED", "false"))
+	if err != nil {
+		return nil, err
+	}
 
 	postgresPort, err := strconv.Atoi(GetEnv("POSTGRES_PORT", "5432"))
 	if err != nil {
@@ -101,7 +173,10 @@ func LoadConfigFromEnv() (*Config, error) {
 		HTTPHost: GetEnv("HOST", ""),
 		HTTPPort: port,
 
-		NuklaiRPC: os.Getenv("NUKLAI_RPC"),
+		NuklaiRPC:     os.Getenv("NUKLAI_RPC"),
+		FinalityDepth: finalityDepth,
+
+		BlockArchiveURL: GetEnv("BLOCK_ARCHIVE_URL", ""),
 
 		Recipient:              GetEnv("RECIPIENT", "nuklai1qpg4ecapjymddcde8sfq06dshzpxltqnl47tvfz0hnkesjz7t0p35d5fnr3"),
 		FeedSize:               feedSize,
@@ -110,7 +185,12 @@ func LoadConfigFromEnv() (*Config, error) {
 		MessagesPerEpoch:       messagesPerEpoch,
 		TargetDurationPerEpoch: targetDurationPerEpoch,
 
-		AdminToken: GetEnv("ADMIN_TOKEN", "ADMIN_TOKEN"),
+		AdminPublicKeys:   adminPublicKeys,
+		AdminToken:        adminToken,
+		AdminTokenEnabled: adminTokenEnabled,
+
+		DatabaseBackend: GetEnv("DATABASE_BACKEND", "postgres"),
+		SQLitePath:      GetEnv("SQLITE_PATH", "nuklai-feed.db"),
 
 		PostgresHost:     GetEnv("POSTGRES_HOST", "localhost"),
 		PostgresPort:     postgresPort,
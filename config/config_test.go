@@ -0,0 +1,33 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTargetDurationPerEpoch(t *testing.T) {
+	tests := []struct {
+		value string
+		err   error
+	}{
+		{"300", nil},
+		{"1", nil},
+		{"0", ErrInvalidEpochDuration},
+		{"-60", ErrInvalidEpochDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TARGET_DURATION_PER_EPOCH", tt.value)
+			config, err := LoadConfigFromEnv()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil && config.TargetDurationPerEpoch <= 0 {
+				t.Fatalf("loaded TargetDurationPerEpoch %d", config.TargetDurationPerEpoch)
+			}
+		})
+	}
+}
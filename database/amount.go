@@ -0,0 +1,82 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// amount stores a uint64 losslessly. It is written as a decimal string into
+// NUMERIC(20,0) columns on Postgres and TEXT columns on SQLite, since neither
+// database has an unsigned 64-bit integer type.
+type amount uint64
+
+func (a amount) Value() (driver.Value, error) {
+	return strconv.FormatUint(uint64(a), 10), nil
+}
+
+func (a *amount) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("negative amount %d", v)
+		}
+		*a = amount(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into amount", src)
+	}
+	parsed, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q: %w", raw, err)
+	}
+	*a = amount(parsed)
+	return nil
+}
+
+// AddAmounts returns [a] + [b], capped at math.MaxUint64.
+func AddAmounts(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// amountBase splits amounts into billions and a remainder, see sumAmounts.
+const amountBase = 1_000_000_000
+
+// sumAmounts returns the SQL expressions summing the amounts in [column] over
+// a group, as the sum of their billions and the sum of their remainders.
+// Neither sum can overflow a signed 64-bit integer, unlike the sum of the
+// amounts themselves, which SQLite cannot compute. Empty groups sum to zero.
+// The sums are joined by joinAmounts.
+func (db *DB) sumAmounts(column string) string {
+	if db.dialect == DialectPostgres {
+		return fmt.Sprintf(`COALESCE(SUM(div(%[1]s, %[2]d)), 0), COALESCE(SUM(mod(%[1]s, %[2]d)), 0)`, column, amountBase)
+	}
+	// Amounts are decimal strings on SQLite: the last nine digits are the
+	// remainder. substr counts a negative start from the end, hence max.
+	return fmt.Sprintf(`COALESCE(SUM(CAST(substr(%[1]s, 1, length(%[1]s) - 9) AS INTEGER)), 0),
+		COALESCE(SUM(CAST(substr(%[1]s, max(length(%[1]s) - 8, 1)) AS INTEGER)), 0)`, column)
+}
+
+// joinAmounts returns the total of the [billions] and [remainders] summed by
+// sumAmounts, capped at math.MaxUint64.
+func joinAmounts(billions, remainders uint64) uint64 {
+	hi, lo := bits.Mul64(billions, amountBase)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return AddAmounts(lo, remainders)
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// postgresDSNEnv names the environment variable holding the DSN of a
+// disposable Postgres database. Postgres tests are skipped when it is unset.
+const postgresDSNEnv = "NUKLAI_FEED_TEST_POSTGRES"
+
+var testAmounts = []uint64{0, 1 << 63, math.MaxUint64}
+
+// testStores returns a fresh memory and SQLite store, and a Postgres store if
+// postgresDSNEnv is set.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	sqlite, err := NewSQLiteDB(filepath.Join(t.TempDir(), "feed.db"))
+	if err != nil {
+		t.Fatalf("opening SQLite: %v", err)
+	}
+	t.Cleanup(sqlite.Close)
+	stores := map[string]Store{
+		"memory": NewMemoryDB(),
+		"sqlite": sqlite,
+	}
+
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		conn, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("opening Postgres: %v", err)
+		}
+		postgres, err := NewPostgresDB(conn)
+		if err != nil {
+			conn.Close()
+			t.Fatalf("migrating Postgres: %v", err)
+		}
+		t.Cleanup(postgres.Close)
+		stores["postgres"] = postgres
+	}
+	return stores
+}
+
+// uniqueID returns an ID that does not collide with rows left in a shared
+// Postgres database by earlier runs.
+func uniqueID(name string) string {
+	return fmt.Sprintf("%s-%d", name, time.Now().UnixNano())
+}
+
+func TestAmountRoundTrip(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, value := range testAmounts {
+				feed := &FeedObject{TxID: uniqueID("feed"), SubnetID: "subnet", ChainID: "chain", Address: "author", Fee: value, Status: StatusFinal}
+				reaction := ReactionObject{TxID: uniqueID("reaction"), TargetTxID: feed.TxID, Address: "reactor", Reaction: "+1", Value: value, Height: uint64(i)}
+				blk := &BlockObject{Height: uint64(i), BlockID: uniqueID("block")}
+				if err := db.IndexBlock(blk, []FeedObject{*feed}, []ReactionObject{reaction}, nil, nil); err != nil {
+					t.Fatalf("indexing fee %d: %v", value, err)
+				}
+
+				got, err := db.GetFeed(feed.TxID, 0)
+				if err != nil {
+					t.Fatalf("reading feed with fee %d: %v", value, err)
+				}
+				if got.Fee != value {
+					t.Errorf("feed fee = %d, want %d", got.Fee, value)
+				}
+
+				totals, err := db.GetReactionTotals([]string{feed.TxID})
+				if err != nil {
+					t.Fatalf("reading reactions with value %d: %v", value, err)
+				}
+				if len(totals) != 1 || totals[0].Tips != value {
+					t.Errorf("reaction totals = %+v, want tips %d", totals, value)
+				}
+
+				epoch := &FeeEpoch{EpochStart: time.Now().UnixNano(), Fee: value}
+				if err := db.SaveFeeEpoch(epoch); err != nil {
+					t.Fatalf("saving fee epoch with fee %d: %v", value, err)
+				}
+				current, ok, err := db.GetCurrentFeeEpoch()
+				if err != nil || !ok {
+					t.Fatalf("reading fee epoch with fee %d: ok = %t, err = %v", value, ok, err)
+				}
+				if current.EpochStart != epoch.EpochStart || current.Fee != value {
+					t.Errorf("current fee epoch = %+v, want %+v", current, epoch)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateBigintFees checks that fees stored as BIGINT before
+// 0002_numeric_fees keep their value when the column is converted.
+func TestMigrateBigintFees(t *testing.T) {
+	conn, err := OpenSQLite(filepath.Join(t.TempDir(), "feed.db"))
+	if err != nil {
+		t.Fatalf("opening SQLite: %v", err)
+	}
+	defer conn.Close()
+
+	migrator, err := NewMigrator(conn, DialectSQLite)
+	if err != nil {
+		t.Fatalf("creating migrator: %v", err)
+	}
+	migrator.migrations = migrator.migrations[:1]
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("applying the initial schema: %v", err)
+	}
+
+	fees := []int64{0, 1, math.MaxInt64}
+	for i, fee := range fees {
+		_, err := conn.Exec(`INSERT INTO feeds (txid, subnetID, chainID, address, timestamp, fee, content) VALUES ($1, 'subnet', 'chain', 'author', $2, $3, $4)`,
+			fmt.Sprintf("tx%d", i), i, fee, `{"message":"legacy"}`)
+		if err != nil {
+			t.Fatalf("inserting legacy feed: %v", err)
+		}
+		_, err = conn.Exec(`INSERT INTO fee_epochs (epochStart, epochEnd, fee, messages) VALUES ($1, $2, $3, 0)`, i, i+1, fee)
+		if err != nil {
+			t.Fatalf("inserting legacy fee epoch: %v", err)
+		}
+	}
+
+	db, err := newDB(conn, DialectSQLite)
+	if err != nil {
+		t.Fatalf("migrating legacy database: %v", err)
+	}
+	history, err := db.GetFeeHistory(len(fees))
+	if err != nil {
+		t.Fatalf("reading fee history: %v", err)
+	}
+	if len(history) != len(fees) {
+		t.Fatalf("got %d fee epochs, want %d", len(history), len(fees))
+	}
+	for i, fee := range fees {
+		feed, err := db.GetFeed(fmt.Sprintf("tx%d", i), 0)
+		if err != nil {
+			t.Fatalf("reading migrated feed: %v", err)
+		}
+		if feed.Fee != uint64(fee) || feed.Message != "legacy" {
+			t.Errorf("migrated feed %d has fee %d and message %q, want %d and %q", i, feed.Fee, feed.Message, fee, "legacy")
+		}
+		// History is newest first.
+		if epoch := history[len(fees)-1-i]; epoch.Fee != uint64(fee) {
+			t.Errorf("migrated fee epoch %d has fee %d, want %d", i, epoch.Fee, fee)
+		}
+	}
+}
+
+// TestTipSums checks that tips are summed exactly past math.MaxInt64 and
+// capped at math.MaxUint64.
+func TestTipSums(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []uint64
+		want   uint64
+	}{
+		{"small", []uint64{1, 999_999_999, 1_000_000_000}, 2_000_000_000},
+		{"past int64", []uint64{1 << 63, 1 << 62, 12345}, 1<<63 + 1<<62 + 12345},
+		{"max", []uint64{math.MaxUint64, 0}, math.MaxUint64},
+		{"saturated", []uint64{math.MaxUint64, 1}, math.MaxUint64},
+		{"saturated carry", []uint64{math.MaxUint64 - 1, math.MaxUint64 - 1}, math.MaxUint64},
+	}
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, tt := range tests {
+				address := uniqueID("author")
+				feed := FeedObject{TxID: uniqueID("feed"), SubnetID: "subnet", ChainID: "chain", Address: address, Status: StatusFinal}
+				reactions := make([]ReactionObject, len(tt.values))
+				for j, value := range tt.values {
+					reactions[j] = ReactionObject{TxID: uniqueID("reaction"), ActionIndex: j, ChainID: "chain", TargetTxID: feed.TxID, Reaction: "+1", Value: value}
+				}
+				blk := &BlockObject{ChainID: "chain", Height: uint64(i), BlockID: uniqueID("block")}
+				if err := db.IndexBlock(blk, []FeedObject{feed}, reactions, nil, nil); err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+
+				totals, err := db.GetReactionTotals([]string{feed.TxID})
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+				if len(totals) != 1 || totals[0].Count != len(tt.values) || totals[0].Tips != tt.want {
+					t.Errorf("%s: reaction totals = %+v, want %d reactions tipping %d", tt.name, totals, len(tt.values), tt.want)
+				}
+				stats, err := db.GetAuthorStats("subnet", "chain", address)
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+				if stats.Reactions != len(tt.values) || stats.Tips != tt.want {
+					t.Errorf("%s: author stats = %+v, want %d reactions tipping %d", tt.name, stats, len(tt.values), tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// BlockObject is a block indexed on [ChainID]. Blocks are kept per chain so
+// that switching chains does not affect the blocks indexed on the previous
+// one.
+type BlockObject struct {
+	ChainID  string `json:"chainID"`
+	Height   uint64 `json:"height"`
+	BlockID  string `json:"blockID"`
+	ParentID string `json:"parentID"`
+	Final    bool   `json:"final"`
+
+	// Messages counts the paid messages of the block, which were recorded in
+	// the fee epoch starting at EpochStart.
+	Messages   int   `json:"messages"`
+	EpochStart int64 `json:"epochStart"`
+}
+
+// BlockGap is a range of heights of [ChainID], from [From] to [To]
+// inclusive, whose blocks could not be fetched. The block cursor moves past a
+// gap only once it is recorded, and the gap shrinks as its blocks are
+// indexed.
+type BlockGap struct {
+	ChainID string `json:"chainID"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+}
+
+// GetBlock returns the block indexed at [height] on [chainID]. The boolean is
+// false if no block has been indexed at that height.
+func (db *DB) GetBlock(chainID string, height uint64) (*BlockObject, bool, error) {
+	var blk BlockObject
+	query := `SELECT chainID, height, blockID, parentID, final, messages, epochStart FROM blocks WHERE chainID = $1 AND height = $2`
+	err := db.conn.QueryRow(query, chainID, height).Scan(&blk.ChainID, &blk.Height, &blk.BlockID, &blk.ParentID, &blk.Final, &blk.Messages, &blk.EpochStart)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		log.Printf("Error fetching block: %v", err)
+		return nil, false, err
+	}
+	return &blk, true, nil
+}
+
+func (db *DB) SaveBlock(blk *BlockObject) error {
+	return saveBlock(db.conn, blk)
+}
+
+func saveBlock(ex execer, blk *BlockObject) error {
+	query := `INSERT INTO blocks (chainID, height, blockID, parentID, final, messages, epochStart) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (chainID, height) DO UPDATE SET blockID = EXCLUDED.blockID, parentID = EXCLUDED.parentID, final = EXCLUDED.final,
+			messages = EXCLUDED.messages, epochStart = EXCLUDED.epochStart`
+	_, err := ex.Exec(query, blk.ChainID, blk.Height, blk.BlockID, blk.ParentID, blk.Final, blk.Messages, blk.EpochStart)
+	if err != nil {
+		log.Printf("Error saving block: %v", err)
+	}
+	return err
+}
+
+// IndexBlock saves [feeds] and [reactions] made in [blk], applies the
+// [revisions] it made in order, records [blk], removes its height from the
+// gaps of its chain, advances the block cursor of its chain to its height and
+// saves the fee [epoch], if not nil, in a single transaction. The cursor is
+// never moved back, so blocks filling a gap can be indexed. Feeds, reactions
+// and revisions that already exist are left untouched, so indexing the same
+// block twice is a no-op.
+func (db *DB) IndexBlock(blk *BlockObject, feeds []FeedObject, reactions []ReactionObject, revisions []RevisionObject, epoch *FeeEpoch) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting block indexing: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for i := range feeds {
+		if err := saveFeed(tx, &feeds[i]); err != nil {
+			return err
+		}
+	}
+	for i := range reactions {
+		if err := saveReaction(tx, &reactions[i]); err != nil {
+			return err
+		}
+	}
+	for i := range revisions {
+		if err := saveRevision(tx, &revisions[i]); err != nil {
+			return err
+		}
+	}
+	if err := saveBlock(tx, blk); err != nil {
+		return err
+	}
+	if err := fillBlockGap(tx, blk.ChainID, blk.Height); err != nil {
+		return err
+	}
+	if err := advanceBlockCursor(tx, blk.ChainID, blk.Height); err != nil {
+		return err
+	}
+	if epoch != nil {
+		if err := saveFeeEpoch(tx, epoch); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing block %d: %v", blk.Height, err)
+		return err
+	}
+	return nil
+}
+
+// RollbackFrom removes every non-final block of [chainID] at or above
+// [height] together with the pending feeds, reactions and revisions they
+// included, takes their messages out of the running fee epoch, drops the gaps
+// at or above [height] and moves the block cursor of [chainID] back to the
+// block before [height].
+func (db *DB) RollbackFrom(chainID string, height uint64) error {
+	log.Printf("Rolling back blocks of chain %s from height: %d", chainID, height)
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting rollback: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`DELETE FROM feeds WHERE chainID = $1 AND height >= $2 AND status = $3`, chainID, height, StatusPending); err != nil {
+		log.Printf("Error rolling back feeds: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM reactions WHERE chainID = $1 AND height >= $2
+		AND height NOT IN (SELECT height FROM blocks WHERE chainID = $1 AND final)`, chainID, height); err != nil {
+		log.Printf("Error rolling back reactions: %v", err)
+		return err
+	}
+	if err := rollbackRevisions(tx, chainID, height); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE fee_epochs SET messages = messages - (SELECT COALESCE(SUM(messages), 0) FROM blocks
+		WHERE chainID = $1 AND height >= $2 AND NOT final AND epochStart = fee_epochs.epochStart)
+		WHERE epochEnd IS NULL`, chainID, height); err != nil {
+		log.Printf("Error rolling back fee epoch messages: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM blocks WHERE chainID = $1 AND height >= $2 AND NOT final`, chainID, height); err != nil {
+		log.Printf("Error rolling back blocks: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM block_gaps WHERE chainID = $1 AND fromHeight >= $2`, chainID, height); err != nil {
+		log.Printf("Error rolling back block gaps: %v", err)
+		return err
+	}
+	if height > 0 {
+		if _, err := tx.Exec(`UPDATE block_gaps SET toHeight = $1 WHERE chainID = $2 AND toHeight >= $3`, height-1, chainID, height); err != nil {
+			log.Printf("Error rolling back block gaps: %v", err)
+			return err
+		}
+		_, err = tx.Exec(`UPDATE block_cursor SET height = $1 WHERE chainID = $2 AND height >= $3`, height-1, chainID, height)
+	} else {
+		_, err = tx.Exec(`DELETE FROM block_cursor WHERE chainID = $1`, chainID)
+	}
+	if err != nil {
+		log.Printf("Error rolling back block cursor: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// FinalizeBlocks marks every block of [chainID] at or below [height] as
+// final and promotes the feeds they included from pending to final.
+func (db *DB) FinalizeBlocks(chainID string, height uint64) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting finalization: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`UPDATE blocks SET final = TRUE WHERE chainID = $1 AND height <= $2 AND NOT final`, chainID, height); err != nil {
+		log.Printf("Error finalizing blocks: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE feeds SET status = $1 WHERE chainID = $2 AND height <= $3 AND status = $4`, StatusFinal, chainID, height, StatusPending); err != nil {
+		log.Printf("Error finalizing feeds: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// SkipBlocks records [gap] and advances the block cursor of its chain to its
+// end in a single transaction.
+func (db *DB) SkipBlocks(gap *BlockGap) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting block skip: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `INSERT INTO block_gaps (chainID, fromHeight, toHeight) VALUES ($1, $2, $3)
+		ON CONFLICT (chainID, fromHeight) DO UPDATE SET toHeight = EXCLUDED.toHeight`
+	if _, err := tx.Exec(query, gap.ChainID, gap.From, gap.To); err != nil {
+		log.Printf("Error saving block gap: %v", err)
+		return err
+	}
+	if err := advanceBlockCursor(tx, gap.ChainID, gap.To); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBlockGaps returns the gaps of [chainID], lowest first.
+func (db *DB) GetBlockGaps(chainID string) ([]BlockGap, error) {
+	rows, err := db.conn.Query(`SELECT chainID, fromHeight, toHeight FROM block_gaps WHERE chainID = $1 ORDER BY fromHeight`, chainID)
+	if err != nil {
+		log.Printf("Error fetching block gaps: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gaps []BlockGap
+	for rows.Next() {
+		var gap BlockGap
+		if err := rows.Scan(&gap.ChainID, &gap.From, &gap.To); err != nil {
+			log.Printf("Error scanning block gap: %v", err)
+			return nil, err
+		}
+		gaps = append(gaps, gap)
+	}
+	return gaps, rows.Err()
+}
+
+// fillBlockGap removes [height] from the gap of [chainID] that contains it,
+// splitting the gap in two if [height] is inside it.
+func fillBlockGap(ex execer, chainID string, height uint64) error {
+	query := `INSERT INTO block_gaps (chainID, fromHeight, toHeight)
+		SELECT chainID, CAST($2 AS BIGINT), toHeight FROM block_gaps WHERE chainID = $1 AND fromHeight <= $3 AND toHeight > $3`
+	if _, err := ex.Exec(query, chainID, height+1, height); err != nil {
+		log.Printf("Error splitting block gap: %v", err)
+		return err
+	}
+	if _, err := ex.Exec(`DELETE FROM block_gaps WHERE chainID = $1 AND fromHeight = $2`, chainID, height); err != nil {
+		log.Printf("Error filling block gap: %v", err)
+		return err
+	}
+	if height == 0 {
+		return nil
+	}
+	query = `UPDATE block_gaps SET toHeight = $1 WHERE chainID = $2 AND fromHeight < $3 AND toHeight >= $3`
+	if _, err := ex.Exec(query, height-1, chainID, height); err != nil {
+		log.Printf("Error filling block gap: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBlockGaps(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			chainID := uniqueID("chain")
+			checkGaps := func(want ...BlockGap) {
+				t.Helper()
+				gaps, err := db.GetBlockGaps(chainID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(gaps, want) {
+					t.Fatalf("gaps = %+v, want %+v", gaps, want)
+				}
+			}
+			checkCursor := func(want uint64) {
+				t.Helper()
+				cursor, ok, err := db.GetBlockCursor(chainID)
+				if err != nil || !ok || cursor != want {
+					t.Fatalf("cursor = %d (%t, %v), want %d", cursor, ok, err, want)
+				}
+			}
+			index := func(height uint64) {
+				t.Helper()
+				blk := &BlockObject{ChainID: chainID, Height: height, BlockID: uniqueID("block")}
+				if err := db.IndexBlock(blk, nil, nil, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			index(2)
+			if err := db.SkipBlocks(&BlockGap{ChainID: chainID, From: 3, To: 9}); err != nil {
+				t.Fatal(err)
+			}
+			checkCursor(9)
+			checkGaps(BlockGap{chainID, 3, 9})
+
+			// Filling a gap splits it and never moves the cursor back.
+			index(5)
+			checkCursor(9)
+			checkGaps(BlockGap{chainID, 3, 4}, BlockGap{chainID, 6, 9})
+			index(3)
+			index(9)
+			checkGaps(BlockGap{chainID, 4, 4}, BlockGap{chainID, 6, 8})
+
+			// Rolling back drops the gaps above the rolled back height.
+			if err := db.RollbackFrom(chainID, 7); err != nil {
+				t.Fatal(err)
+			}
+			checkCursor(6)
+			checkGaps(BlockGap{chainID, 4, 4}, BlockGap{chainID, 6, 6})
+			if err := db.RollbackFrom(chainID, 4); err != nil {
+				t.Fatal(err)
+			}
+			checkCursor(3)
+			checkGaps()
+		})
+	}
+}
+
+func TestRollbackFeeEpochMessages(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			chainID := uniqueID("chain")
+			// Each store starts its own epochs, after those of earlier runs.
+			closed := time.Now().UnixNano()
+			running := closed + 1
+			if err := db.RotateFeeEpoch(&FeeEpoch{EpochStart: closed, EpochEnd: running, Fee: 100, Messages: 2}, &FeeEpoch{EpochStart: running, Fee: 100}); err != nil {
+				t.Fatal(err)
+			}
+			blocks := []*BlockObject{
+				{ChainID: chainID, Height: 1, BlockID: uniqueID("block"), Messages: 2, EpochStart: closed},
+				{ChainID: chainID, Height: 2, BlockID: uniqueID("block"), Messages: 1, EpochStart: running},
+				{ChainID: chainID, Height: 3, BlockID: uniqueID("block"), Messages: 3, EpochStart: running},
+				{ChainID: chainID, Height: 4, BlockID: uniqueID("block"), Messages: 4, EpochStart: running},
+			}
+			messages := 0
+			for _, blk := range blocks {
+				var epoch *FeeEpoch
+				if blk.EpochStart == running {
+					messages += blk.Messages
+					epoch = &FeeEpoch{EpochStart: running, Fee: 100, Messages: messages}
+				}
+				if err := db.IndexBlock(blk, nil, nil, nil, epoch); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := db.FinalizeBlocks(chainID, 2); err != nil {
+				t.Fatal(err)
+			}
+			checkEpochs := func(closedMessages, runningMessages int) {
+				t.Helper()
+				current, ok, err := db.GetCurrentFeeEpoch()
+				if err != nil || !ok || current.EpochStart != running || current.Messages != runningMessages {
+					t.Fatalf("running epoch = %+v (%t, %v), want %d messages", current, ok, err, runningMessages)
+				}
+				history, err := db.GetFeeHistory(2)
+				if err != nil || len(history) != 2 || history[1].EpochStart != closed || history[1].Messages != closedMessages {
+					t.Fatalf("fee history = %+v (%v), want %d messages in the closed epoch", history, err, closedMessages)
+				}
+			}
+			checkEpochs(2, 8)
+
+			if err := db.RollbackFrom(chainID, 4); err != nil {
+				t.Fatal(err)
+			}
+			checkEpochs(2, 4)
+			// Final blocks keep their messages, and so does the closed epoch.
+			if err := db.RollbackFrom(chainID, 1); err != nil {
+				t.Fatal(err)
+			}
+			checkEpochs(2, 1)
+		})
+	}
+}
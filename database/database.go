@@ -5,90 +5,93 @@ package database
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
-
-	_ "github.com/lib/pq"
+	"slices"
 )
 
+var _ Store = (*DB)(nil)
+
+// DB is a Store backed by a SQL database. The same queries are used for
+// Postgres and SQLite; only the migrations differ.
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB
+	dialect string
 }
 
+// ErrNotFound is returned when a requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Feed statuses. A feed is pending until the block that included it is final.
+const (
+	StatusPending = "pending"
+	StatusFinal   = "final"
+)
+
+const feedColumns = `txid, actionIndex, subnetID, chainID, address, timestamp, fee, message, url, encoding, blockID, height, status, hidden,
+	parentTxID, parentActionIndex, rootTxID, rootActionIndex, revision, deleted`
+
+// FeedObject is a post made by a transfer to the feed. Posts are keyed by the
+// transaction ID and the index of the transfer within the transaction.
 type FeedObject struct {
-	TxID      string `json:"txID"`
-	SubnetID  string `json:"subnetID"`
-	ChainID   string `json:"chainID"`
-	Address   string `json:"address"`
-	Timestamp int64  `json:"timestamp"`
-	Fee       uint64 `json:"fee"`
-	Content   string `json:"content"` // JSON-encoded content
-}
-
-func NewDB(conn *sql.DB) (*DB, error) {
-	db := &DB{conn: conn}
-
-	query := `CREATE TABLE IF NOT EXISTS feeds (
-		txid TEXT PRIMARY KEY,
-		subnetID TEXT,
-		chainID TEXT,
-		address TEXT,
-		timestamp BIGINT,
-		fee BIGINT,
-		content TEXT
-	)`
-	_, err := db.conn.Exec(query)
-	if err != nil {
-		log.Printf("Error creating table: %v", err)
-		return nil, err
-	}
+	TxID        string `json:"txID"`
+	ActionIndex int    `json:"actionIndex"`
+	SubnetID    string `json:"subnetID"`
+	ChainID     string `json:"chainID"`
+	Address     string `json:"address"`
+	Timestamp   int64  `json:"timestamp"`
+	Fee         uint64 `json:"fee"`
+	Message     string `json:"message"`
+	URL         string `json:"url"`
+	Encoding    string `json:"encoding"` // name of the memo decoder
+	BlockID     string `json:"blockID"`
+	Height      uint64 `json:"height"`
+	Status      string `json:"status"`
+	Hidden      bool   `json:"hidden"` // hidden posts are never served
+
+	// Replies reference the post they answer and the first post of their
+	// thread. Both are empty for other posts.
+	ParentTxID        string `json:"parentTxID"`
+	ParentActionIndex int    `json:"parentActionIndex"`
+	RootTxID          string `json:"rootTxID"`
+	RootActionIndex   int    `json:"rootActionIndex"`
 
-	log.Println("Database initialized successfully")
-	return db, nil
+	// Revision counts the edits and deletions of the feed by its author, see
+	// RevisionObject. Deleted feeds have an empty message and URL.
+	Revision int  `json:"revision"`
+	Deleted  bool `json:"deleted"`
 }
 
-func (db *DB) SaveFeed(feed *FeedObject) error {
-	log.Printf("Saving feed with TxID: %s", feed.TxID)
-	query := `INSERT INTO feeds (txid, subnetID, chainID, address, timestamp, fee, content) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := db.conn.Exec(query, feed.TxID, feed.SubnetID, feed.ChainID, feed.Address, feed.Timestamp, feed.Fee, feed.Content)
-	if err != nil {
-		log.Printf("Error saving feed: %v", err)
-	}
-	return err
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx, so writes can run on
+// their own or as part of a transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
 }
 
-func (db *DB) GetFeed(txID string) (*FeedObject, error) {
+func scanFeed(row rowScanner) (*FeedObject, error) {
 	var feed FeedObject
-	query := `SELECT txid, subnetID, chainID, address, timestamp, fee, content FROM feeds WHERE txid = $1`
-	row := db.conn.QueryRow(query, txID)
-	err := row.Scan(&feed.TxID, &feed.SubnetID, &feed.ChainID, &feed.Address, &feed.Timestamp, &feed.Fee, &feed.Content)
+	err := row.Scan(&feed.TxID, &feed.ActionIndex, &feed.SubnetID, &feed.ChainID, &feed.Address, &feed.Timestamp, (*amount)(&feed.Fee), &feed.Message, &feed.URL, &feed.Encoding, &feed.BlockID, &feed.Height, &feed.Status, &feed.Hidden,
+		&feed.ParentTxID, &feed.ParentActionIndex, &feed.RootTxID, &feed.RootActionIndex, &feed.Revision, &feed.Deleted)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("No feed found with TxID: %s", txID)
-		} else {
-			log.Printf("Error fetching feed: %v", err)
-		}
 		return nil, err
 	}
 	return &feed, nil
 }
 
-func (db *DB) GetAllFeeds() ([]FeedObject, error) {
+func scanFeeds(rows *sql.Rows) ([]FeedObject, error) {
 	var feeds []FeedObject
-	query := `SELECT txid, subnetID, chainID, address, timestamp, fee, content FROM feeds`
-	rows, err := db.conn.Query(query)
-	if err != nil {
-		log.Printf("Error fetching all feeds: %v", err)
-		return nil, err
-	}
-	defer rows.Close()
-
 	for rows.Next() {
-		var feed FeedObject
-		if err := rows.Scan(&feed.TxID, &feed.SubnetID, &feed.ChainID, &feed.Address, &feed.Timestamp, &feed.Fee, &feed.Content); err != nil {
+		feed, err := scanFeed(rows)
+		if err != nil {
 			log.Printf("Error scanning feed row: %v", err)
 			return nil, err
 		}
-		feeds = append(feeds, feed)
+		feeds = append(feeds, *feed)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -99,60 +102,189 @@ func (db *DB) GetAllFeeds() ([]FeedObject, error) {
 	return feeds, nil
 }
 
-func (db *DB) GetFeedsByUser(address string) ([]FeedObject, error) {
-	var feeds []FeedObject
-	query := `SELECT txid, subnetID, chainID, address, timestamp, fee, content FROM feeds WHERE address = $1`
-	rows, err := db.conn.Query(query, address)
+// newDB migrates the schema of [conn] to the latest version and returns a DB
+// using it. Databases migrated by a newer release are rejected with
+// ErrSchemaTooNew.
+func newDB(conn *sql.DB, dialect string) (*DB, error) {
+	migrator, err := NewMigrator(conn, dialect)
 	if err != nil {
-		log.Printf("Error fetching feeds by user: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
+	applied, err := migrator.Up()
+	if err != nil {
+		log.Printf("Error migrating schema: %v", err)
+		return nil, err
+	}
 
-	for rows.Next() {
-		var feed FeedObject
-		if err := rows.Scan(&feed.TxID, &feed.SubnetID, &feed.ChainID, &feed.Address, &feed.Timestamp, &feed.Fee, &feed.Content); err != nil {
-			log.Printf("Error scanning feed row: %v", err)
-			return nil, err
+	log.Printf("Database initialized successfully at schema version %d (%d migrations applied)", migrator.Latest(), applied)
+	return &DB{conn: conn, dialect: dialect}, nil
+}
+
+// SaveFeed inserts [feed]. Saving a feed that already exists is a no-op.
+func (db *DB) SaveFeed(feed *FeedObject) error {
+	return saveFeed(db.conn, feed)
+}
+
+func saveFeed(ex execer, feed *FeedObject) error {
+	log.Printf("Saving feed with TxID: %s, action: %d", feed.TxID, feed.ActionIndex)
+	query := `INSERT INTO feeds (` + feedColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		ON CONFLICT (txid, actionIndex) DO NOTHING`
+	_, err := ex.Exec(query, feed.TxID, feed.ActionIndex, feed.SubnetID, feed.ChainID, feed.Address, feed.Timestamp, amount(feed.Fee), feed.Message, feed.URL, feed.Encoding, feed.BlockID, feed.Height, feed.Status, feed.Hidden,
+		feed.ParentTxID, feed.ParentActionIndex, feed.RootTxID, feed.RootActionIndex, feed.Revision, feed.Deleted)
+	if err != nil {
+		log.Printf("Error saving feed: %v", err)
+	}
+	return err
+}
+
+// GetFeed returns the feed posted by action [actionIndex] of [txID], or
+// ErrNotFound. Hidden feeds are never returned by the getters.
+func (db *DB) GetFeed(txID string, actionIndex int) (*FeedObject, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE txid = $1 AND actionIndex = $2 AND NOT hidden`
+	feed, err := scanFeed(db.conn.QueryRow(query, txID, actionIndex))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("No feed found with TxID: %s, action: %d", txID, actionIndex)
+			return nil, ErrNotFound
 		}
-		feeds = append(feeds, feed)
+		log.Printf("Error fetching feed: %v", err)
+		return nil, err
 	}
+	return feed, nil
+}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error in rows: %v", err)
+// GetFeedsByTxID returns every feed posted in [txID], ordered by action
+// index.
+func (db *DB) GetFeedsByTxID(txID string) ([]FeedObject, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE txid = $1 AND NOT hidden ORDER BY actionIndex`
+	rows, err := db.conn.Query(query, txID)
+	if err != nil {
+		log.Printf("Error fetching feeds by TxID: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	return feeds, nil
+	return scanFeeds(rows)
 }
 
-func (db *DB) GetLastFeeds(limit int) ([]FeedObject, error) {
-	var feeds []FeedObject
-	query := `SELECT txid, subnetID, chainID, address, timestamp, fee, content FROM feeds ORDER BY timestamp DESC LIMIT $1`
-	rows, err := db.conn.Query(query, limit)
+func (db *DB) GetAllFeeds() ([]FeedObject, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds`
+	rows, err := db.conn.Query(query)
 	if err != nil {
-		log.Printf("Error fetching last feeds: %v", err)
+		log.Printf("Error fetching all feeds: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var feed FeedObject
-		if err := rows.Scan(&feed.TxID, &feed.SubnetID, &feed.ChainID, &feed.Address, &feed.Timestamp, &feed.Fee, &feed.Content); err != nil {
-			log.Printf("Error scanning feed row: %v", err)
-			return nil, err
+	return scanFeeds(rows)
+}
+
+// GetFeedsByUser returns up to [limit] feeds posted by [address] on the given
+// subnet and chain, paginated like GetLastFeeds.
+func (db *DB) GetFeedsByUser(subnetID, chainID, address string, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	feeds, err := db.queryFeedPage(`subnetID = $1 AND chainID = $2 AND address = $3`, []any{subnetID, chainID, address}, cursor, limit)
+	if err != nil {
+		log.Printf("Error fetching feeds by user: %v", err)
+	}
+	return feeds, err
+}
+
+// PageCursor is a position in the feed ordering, which is newest first by
+// timestamp, then by txid and then by action index.
+type PageCursor struct {
+	Timestamp   int64
+	TxID        string
+	ActionIndex int
+	// Newer selects feeds newer than the cursor instead of older ones.
+	Newer bool
+}
+
+// GetLastFeeds returns up to [limit] feeds posted on the given subnet and
+// chain, newest first. If [cursor] is set, only feeds on its side of the
+// cursor are returned, starting with the ones closest to it.
+func (db *DB) GetLastFeeds(subnetID, chainID string, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	feeds, err := db.queryFeedPage(`subnetID = $1 AND chainID = $2`, []any{subnetID, chainID}, cursor, limit)
+	if err != nil {
+		log.Printf("Error fetching last feeds: %v", err)
+	}
+	return feeds, err
+}
+
+// queryFeedPage returns up to [limit] feeds matching [filter], newest first,
+// starting next to [cursor] if it is set. [filter] uses placeholders $1 to
+// $len(args).
+func (db *DB) queryFeedPage(filter string, args []any, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	filter += ` AND NOT hidden`
+	order := `ORDER BY timestamp DESC, txid DESC, actionIndex DESC`
+	if cursor != nil {
+		op := "<"
+		if cursor.Newer {
+			op = ">"
+			order = `ORDER BY timestamp ASC, txid ASC, actionIndex ASC`
 		}
-		feeds = append(feeds, feed)
+		filter += fmt.Sprintf(` AND (timestamp, txid, actionIndex) %s ($%d, $%d, $%d)`, op, len(args)+1, len(args)+2, len(args)+3)
+		args = append(args, cursor.Timestamp, cursor.TxID, cursor.ActionIndex)
 	}
+	query := fmt.Sprintf(`SELECT %s FROM feeds WHERE %s %s LIMIT $%d`, feedColumns, filter, order, len(args)+1)
+	args = append(args, limit)
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error in rows: %v", err)
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	feeds, err := scanFeeds(rows)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && cursor.Newer {
+		slices.Reverse(feeds)
+	}
 	return feeds, nil
 }
 
+// GetBlockCursor returns the height of the last processed block of
+// [chainID]. The boolean is false if no block has been processed yet.
+func (db *DB) GetBlockCursor(chainID string) (uint64, bool, error) {
+	var height uint64
+	query := `SELECT height FROM block_cursor WHERE chainID = $1`
+	err := db.conn.QueryRow(query, chainID).Scan(&height)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		log.Printf("Error fetching block cursor: %v", err)
+		return 0, false, err
+	}
+	return height, true, nil
+}
+
+func (db *DB) SaveBlockCursor(chainID string, height uint64) error {
+	return saveBlockCursor(db.conn, chainID, height)
+}
+
+func saveBlockCursor(ex execer, chainID string, height uint64) error {
+	query := `INSERT INTO block_cursor (chainID, height) VALUES ($1, $2) ON CONFLICT (chainID) DO UPDATE SET height = EXCLUDED.height`
+	_, err := ex.Exec(query, chainID, height)
+	if err != nil {
+		log.Printf("Error saving block cursor: %v", err)
+	}
+	return err
+}
+
+// advanceBlockCursor moves the block cursor of [chainID] to [height] unless
+// it is already past it.
+func advanceBlockCursor(ex execer, chainID string, height uint64) error {
+	query := `INSERT INTO block_cursor (chainID, height) VALUES ($1, $2)
+		ON CONFLICT (chainID) DO UPDATE SET height = EXCLUDED.height WHERE block_cursor.height < EXCLUDED.height`
+	_, err := ex.Exec(query, chainID, height)
+	if err != nil {
+		log.Printf("Error advancing block cursor: %v", err)
+	}
+	return err
+}
+
 func (db *DB) Close() {
 	log.Println("Closing database connection")
 	db.conn.Close()
@@ -0,0 +1,114 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// FeeEpoch records the fee charged during an epoch and how many posts were
+// accepted. EpochEnd is zero for the epoch that is still running.
+type FeeEpoch struct {
+	EpochStart int64  `json:"epochStart"`
+	EpochEnd   int64  `json:"epochEnd"`
+	Fee        uint64 `json:"fee"`
+	Messages   int    `json:"messages"`
+}
+
+const feeEpochColumns = `epochStart, epochEnd, fee, messages`
+
+func scanFeeEpoch(row rowScanner) (*FeeEpoch, error) {
+	var (
+		epoch FeeEpoch
+		end   sql.NullInt64
+	)
+	if err := row.Scan(&epoch.EpochStart, &end, (*amount)(&epoch.Fee), &epoch.Messages); err != nil {
+		return nil, err
+	}
+	epoch.EpochEnd = end.Int64
+	return &epoch, nil
+}
+
+// SaveFeeEpoch inserts [epoch] or updates the epoch with the same start.
+func (db *DB) SaveFeeEpoch(epoch *FeeEpoch) error {
+	return saveFeeEpoch(db.conn, epoch)
+}
+
+func saveFeeEpoch(ex execer, epoch *FeeEpoch) error {
+	end := sql.NullInt64{Int64: epoch.EpochEnd, Valid: epoch.EpochEnd != 0}
+	query := `INSERT INTO fee_epochs (` + feeEpochColumns + `) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (epochStart) DO UPDATE SET epochEnd = EXCLUDED.epochEnd, fee = EXCLUDED.fee, messages = EXCLUDED.messages`
+	_, err := ex.Exec(query, epoch.EpochStart, end, amount(epoch.Fee), epoch.Messages)
+	if err != nil {
+		log.Printf("Error saving fee epoch: %v", err)
+	}
+	return err
+}
+
+// RotateFeeEpoch saves the [closed] epoch and the [opened] one that follows
+// it in a single transaction, so there is always exactly one running epoch.
+func (db *DB) RotateFeeEpoch(closed, opened *FeeEpoch) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting fee epoch rotation: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := saveFeeEpoch(tx, closed); err != nil {
+		return err
+	}
+	if err := saveFeeEpoch(tx, opened); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing fee epoch rotation: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetCurrentFeeEpoch returns the epoch that is still running. The boolean is
+// false if no epoch has been recorded yet.
+func (db *DB) GetCurrentFeeEpoch() (*FeeEpoch, bool, error) {
+	query := `SELECT ` + feeEpochColumns + ` FROM fee_epochs WHERE epochEnd IS NULL ORDER BY epochStart DESC LIMIT 1`
+	epoch, err := scanFeeEpoch(db.conn.QueryRow(query))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		log.Printf("Error fetching current fee epoch: %v", err)
+		return nil, false, err
+	}
+	return epoch, true, nil
+}
+
+// GetFeeHistory returns the [limit] most recent epochs, newest first.
+func (db *DB) GetFeeHistory(limit int) ([]FeeEpoch, error) {
+	query := `SELECT ` + feeEpochColumns + ` FROM fee_epochs ORDER BY epochStart DESC LIMIT $1`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		log.Printf("Error fetching fee history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var epochs []FeeEpoch
+	for rows.Next() {
+		epoch, err := scanFeeEpoch(rows)
+		if err != nil {
+			log.Printf("Error scanning fee epoch row: %v", err)
+			return nil, err
+		}
+		epochs = append(epochs, *epoch)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+
+	return epochs, nil
+}
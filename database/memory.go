@@ -0,0 +1,616 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var _ Store = (*MemoryDB)(nil)
+
+// MemoryDB is a Store that keeps everything in memory. Its contents are lost
+// when the process exits, so it is meant for local runs and unit tests.
+type MemoryDB struct {
+	l sync.RWMutex
+
+	feeds     map[feedKey]FeedObject
+	reactions map[feedKey]ReactionObject
+	revisions map[feedKey][]RevisionObject // ordered by revision
+	blocks    map[blockKey]BlockObject
+	cursors   map[string]uint64     // by chain
+	gaps      map[blockKey]BlockGap // by chain and start
+	feeEpochs map[int64]FeeEpoch
+	banned    map[string]int64
+	modLog    []ModerationEntry
+}
+
+type feedKey struct {
+	txID        string
+	actionIndex int
+}
+
+type blockKey struct {
+	chainID string
+	height  uint64
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		feeds:     map[feedKey]FeedObject{},
+		reactions: map[feedKey]ReactionObject{},
+		revisions: map[feedKey][]RevisionObject{},
+		blocks:    map[blockKey]BlockObject{},
+		cursors:   map[string]uint64{},
+		gaps:      map[blockKey]BlockGap{},
+		feeEpochs: map[int64]FeeEpoch{},
+		banned:    map[string]int64{},
+	}
+}
+
+// compareFeed orders [feed] against the position [cursor] in ascending feed
+// order. The direction of [cursor] is ignored.
+func compareFeed(feed *FeedObject, cursor *PageCursor) int {
+	if c := cmp.Compare(feed.Timestamp, cursor.Timestamp); c != 0 {
+		return c
+	}
+	if c := strings.Compare(feed.TxID, cursor.TxID); c != 0 {
+		return c
+	}
+	return cmp.Compare(feed.ActionIndex, cursor.ActionIndex)
+}
+
+// sortNewestFirst sorts [feeds] in the order used by the SQL stores.
+func sortNewestFirst(feeds []FeedObject) {
+	slices.SortFunc(feeds, func(a, b FeedObject) int {
+		return -compareFeed(&a, &PageCursor{Timestamp: b.Timestamp, TxID: b.TxID, ActionIndex: b.ActionIndex})
+	})
+}
+
+// feedPage mirrors DB.queryFeedPage. The caller must hold the lock.
+func (db *MemoryDB) feedPage(match func(*FeedObject) bool, cursor *PageCursor, limit int) []FeedObject {
+	var feeds []FeedObject
+	for _, feed := range db.feeds {
+		if feed.Hidden || !match(&feed) {
+			continue
+		}
+		if cursor != nil {
+			c := compareFeed(&feed, cursor)
+			if (cursor.Newer && c <= 0) || (!cursor.Newer && c >= 0) {
+				continue
+			}
+		}
+		feeds = append(feeds, feed)
+	}
+	sortNewestFirst(feeds)
+
+	if len(feeds) > limit {
+		if cursor != nil && cursor.Newer {
+			// Keep the feeds closest to the cursor.
+			feeds = feeds[len(feeds)-limit:]
+		} else {
+			feeds = feeds[:limit]
+		}
+	}
+	return feeds
+}
+
+func (db *MemoryDB) SaveFeed(feed *FeedObject) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.saveFeed(feed)
+	return nil
+}
+
+// saveFeed stores [feed] unless it already exists. The caller must hold the
+// lock.
+func (db *MemoryDB) saveFeed(feed *FeedObject) {
+	key := feedKey{feed.TxID, feed.ActionIndex}
+	if _, ok := db.feeds[key]; !ok {
+		db.feeds[key] = *feed
+	}
+}
+
+func (db *MemoryDB) GetFeed(txID string, actionIndex int) (*FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	feed, ok := db.feeds[feedKey{txID, actionIndex}]
+	if !ok || feed.Hidden {
+		return nil, ErrNotFound
+	}
+	return &feed, nil
+}
+
+func (db *MemoryDB) GetFeedsByTxID(txID string) ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	var feeds []FeedObject
+	for _, feed := range db.feeds {
+		if feed.TxID == txID && !feed.Hidden {
+			feeds = append(feeds, feed)
+		}
+	}
+	slices.SortFunc(feeds, func(a, b FeedObject) int {
+		return cmp.Compare(a.ActionIndex, b.ActionIndex)
+	})
+	return feeds, nil
+}
+
+func (db *MemoryDB) GetAllFeeds() ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	feeds := make([]FeedObject, 0, len(db.feeds))
+	for _, feed := range db.feeds {
+		feeds = append(feeds, feed)
+	}
+	sortNewestFirst(feeds)
+	return feeds, nil
+}
+
+func (db *MemoryDB) GetFeedsByUser(subnetID, chainID, address string, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	return db.feedPage(func(feed *FeedObject) bool {
+		return feed.SubnetID == subnetID && feed.ChainID == chainID && feed.Address == address
+	}, cursor, limit), nil
+}
+
+func (db *MemoryDB) GetLastFeeds(subnetID, chainID string, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	return db.feedPage(func(feed *FeedObject) bool {
+		return feed.SubnetID == subnetID && feed.ChainID == chainID
+	}, cursor, limit), nil
+}
+
+func (db *MemoryDB) SearchFeeds(query *SearchQuery, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	return db.feedPage(func(feed *FeedObject) bool {
+		if feed.SubnetID != query.SubnetID || feed.ChainID != query.ChainID {
+			return false
+		}
+		if len(query.Address) > 0 && feed.Address != query.Address {
+			return false
+		}
+		if (query.From > 0 && feed.Timestamp < query.From) || (query.To > 0 && feed.Timestamp >= query.To) {
+			return false
+		}
+		message := strings.ToLower(feed.Message)
+		for _, term := range terms {
+			if !strings.Contains(message, term) {
+				return false
+			}
+		}
+		return true
+	}, cursor, limit), nil
+}
+
+func (db *MemoryDB) GetThread(txID string, actionIndex int, limit int) ([]FeedObject, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	var feeds []FeedObject
+	for key, feed := range db.feeds {
+		if feed.Hidden {
+			continue
+		}
+		if key == (feedKey{txID, actionIndex}) || (feed.RootTxID == txID && feed.RootActionIndex == actionIndex) {
+			feeds = append(feeds, feed)
+		}
+	}
+	sortNewestFirst(feeds)
+	slices.Reverse(feeds)
+	if len(feeds) > limit {
+		feeds = feeds[:limit]
+	}
+	return feeds, nil
+}
+
+func (db *MemoryDB) CountReplies(txID string, actionIndex int) (int, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	count := 0
+	for _, feed := range db.feeds {
+		if !feed.Hidden && feed.ParentTxID == txID && feed.ParentActionIndex == actionIndex {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *MemoryDB) GetBlockCursor(chainID string) (uint64, bool, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	cursor, ok := db.cursors[chainID]
+	return cursor, ok, nil
+}
+
+func (db *MemoryDB) SaveBlockCursor(chainID string, height uint64) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.cursors[chainID] = height
+	return nil
+}
+
+func (db *MemoryDB) GetBlock(chainID string, height uint64) (*BlockObject, bool, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	blk, ok := db.blocks[blockKey{chainID, height}]
+	if !ok {
+		return nil, false, nil
+	}
+	return &blk, true, nil
+}
+
+func (db *MemoryDB) SaveBlock(blk *BlockObject) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.blocks[blockKey{blk.ChainID, blk.Height}] = *blk
+	return nil
+}
+
+func (db *MemoryDB) GetReactionTotals(txIDs []string) ([]ReactionTotal, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	type totalKey struct {
+		target   feedKey
+		reaction string
+	}
+	totals := map[totalKey]*ReactionTotal{}
+	for _, reaction := range db.reactions {
+		if !slices.Contains(txIDs, reaction.TargetTxID) {
+			continue
+		}
+		key := totalKey{feedKey{reaction.TargetTxID, reaction.TargetActionIndex}, reaction.Reaction}
+		total, ok := totals[key]
+		if !ok {
+			total = &ReactionTotal{TargetTxID: reaction.TargetTxID, TargetActionIndex: reaction.TargetActionIndex, Reaction: reaction.Reaction}
+			totals[key] = total
+		}
+		total.Count++
+		total.Tips = AddAmounts(total.Tips, reaction.Value)
+	}
+	result := make([]ReactionTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	slices.SortFunc(result, func(a, b ReactionTotal) int {
+		if c := strings.Compare(a.TargetTxID, b.TargetTxID); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.TargetActionIndex, b.TargetActionIndex); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Reaction, b.Reaction)
+	})
+	return result, nil
+}
+
+func (db *MemoryDB) GetAuthorStats(subnetID, chainID, address string) (*AuthorStats, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	authored := func(key feedKey) bool {
+		feed, ok := db.feeds[key]
+		return ok && !feed.Hidden && feed.SubnetID == subnetID && feed.ChainID == chainID && feed.Address == address
+	}
+	var stats AuthorStats
+	for key := range db.feeds {
+		if authored(key) && !db.feeds[key].Deleted {
+			stats.Posts++
+		}
+	}
+	for _, reaction := range db.reactions {
+		if authored(feedKey{reaction.TargetTxID, reaction.TargetActionIndex}) {
+			stats.Reactions++
+			stats.Tips = AddAmounts(stats.Tips, reaction.Value)
+		}
+	}
+	return &stats, nil
+}
+
+// applyRevision mirrors applyRevision. The caller must hold the lock.
+func (db *MemoryDB) applyRevision(rev *RevisionObject) {
+	key := feedKey{rev.TxID, rev.ActionIndex}
+	feed, ok := db.feeds[key]
+	if !ok {
+		return
+	}
+	feed.Message, feed.URL, feed.Encoding = rev.Message, rev.URL, rev.Encoding
+	feed.Revision, feed.Deleted = rev.Revision, rev.Action == RevisionDelete
+	db.feeds[key] = feed
+}
+
+func (db *MemoryDB) IndexBlock(blk *BlockObject, feeds []FeedObject, reactions []ReactionObject, revisions []RevisionObject, epoch *FeeEpoch) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	for i := range feeds {
+		db.saveFeed(&feeds[i])
+	}
+	for _, reaction := range reactions {
+		key := feedKey{reaction.TxID, reaction.ActionIndex}
+		if _, ok := db.reactions[key]; !ok {
+			db.reactions[key] = reaction
+		}
+	}
+	for _, rev := range revisions {
+		key := feedKey{rev.TxID, rev.ActionIndex}
+		if slices.ContainsFunc(db.revisions[key], func(r RevisionObject) bool { return r.Revision == rev.Revision }) {
+			continue
+		}
+		db.revisions[key] = append(db.revisions[key], rev)
+		slices.SortFunc(db.revisions[key], func(a, b RevisionObject) int { return cmp.Compare(a.Revision, b.Revision) })
+		if rev.Revision > 0 {
+			db.applyRevision(&rev)
+		}
+	}
+	db.blocks[blockKey{blk.ChainID, blk.Height}] = *blk
+	db.fillGap(blk.ChainID, blk.Height)
+	db.advanceCursor(blk.ChainID, blk.Height)
+	if epoch != nil {
+		db.feeEpochs[epoch.EpochStart] = *epoch
+	}
+	return nil
+}
+
+func (db *MemoryDB) RollbackFrom(chainID string, height uint64) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	// rolledBack reports whether a row indexed at [h] on [chain] is rolled
+	// back.
+	rolledBack := func(chain string, h uint64) bool {
+		blk, ok := db.blocks[blockKey{chain, h}]
+		return chain == chainID && h >= height && (!ok || !blk.Final)
+	}
+	for key, feed := range db.feeds {
+		if feed.ChainID == chainID && feed.Height >= height && feed.Status == StatusPending {
+			delete(db.feeds, key)
+		}
+	}
+	for key, reaction := range db.reactions {
+		if rolledBack(reaction.ChainID, reaction.Height) {
+			delete(db.reactions, key)
+		}
+	}
+	for key, revs := range db.revisions {
+		kept := slices.DeleteFunc(slices.Clone(revs), func(rev RevisionObject) bool {
+			return rolledBack(rev.ChainID, rev.Height)
+		})
+		if len(kept) == len(revs) {
+			continue
+		}
+		if len(kept) == 0 {
+			delete(db.revisions, key)
+			continue
+		}
+		db.revisions[key] = kept
+		db.applyRevision(&kept[len(kept)-1])
+	}
+	for key, blk := range db.blocks {
+		if key.chainID == chainID && key.height >= height && !blk.Final {
+			if epoch, ok := db.feeEpochs[blk.EpochStart]; ok && epoch.EpochEnd == 0 {
+				epoch.Messages -= blk.Messages
+				db.feeEpochs[blk.EpochStart] = epoch
+			}
+			delete(db.blocks, key)
+		}
+	}
+	for key, gap := range db.gaps {
+		switch {
+		case key.chainID != chainID || gap.To < height:
+		case gap.From >= height:
+			delete(db.gaps, key)
+		default:
+			gap.To = height - 1
+			db.gaps[key] = gap
+		}
+	}
+	if cursor, ok := db.cursors[chainID]; ok && cursor >= height {
+		if height > 0 {
+			db.cursors[chainID] = height - 1
+		} else {
+			delete(db.cursors, chainID)
+		}
+	}
+	return nil
+}
+
+// advanceCursor mirrors advanceBlockCursor. The caller must hold the lock.
+func (db *MemoryDB) advanceCursor(chainID string, height uint64) {
+	if cursor, ok := db.cursors[chainID]; !ok || cursor < height {
+		db.cursors[chainID] = height
+	}
+}
+
+// fillGap mirrors fillBlockGap. The caller must hold the lock.
+func (db *MemoryDB) fillGap(chainID string, height uint64) {
+	for key, gap := range db.gaps {
+		if key.chainID != chainID || gap.From > height || gap.To < height {
+			continue
+		}
+		delete(db.gaps, key)
+		if gap.From < height {
+			db.gaps[key] = BlockGap{ChainID: chainID, From: gap.From, To: height - 1}
+		}
+		if gap.To > height {
+			db.gaps[blockKey{chainID, height + 1}] = BlockGap{ChainID: chainID, From: height + 1, To: gap.To}
+		}
+		return
+	}
+}
+
+func (db *MemoryDB) SkipBlocks(gap *BlockGap) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.gaps[blockKey{gap.ChainID, gap.From}] = *gap
+	db.advanceCursor(gap.ChainID, gap.To)
+	return nil
+}
+
+func (db *MemoryDB) GetBlockGaps(chainID string) ([]BlockGap, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	var gaps []BlockGap
+	for key, gap := range db.gaps {
+		if key.chainID == chainID {
+			gaps = append(gaps, gap)
+		}
+	}
+	slices.SortFunc(gaps, func(a, b BlockGap) int { return cmp.Compare(a.From, b.From) })
+	return gaps, nil
+}
+
+func (db *MemoryDB) FinalizeBlocks(chainID string, height uint64) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	for key, blk := range db.blocks {
+		if key.chainID == chainID && key.height <= height && !blk.Final {
+			blk.Final = true
+			db.blocks[key] = blk
+		}
+	}
+	for key, feed := range db.feeds {
+		if feed.ChainID == chainID && feed.Height <= height && feed.Status == StatusPending {
+			feed.Status = StatusFinal
+			db.feeds[key] = feed
+		}
+	}
+	return nil
+}
+
+func (db *MemoryDB) SaveFeeEpoch(epoch *FeeEpoch) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.feeEpochs[epoch.EpochStart] = *epoch
+	return nil
+}
+
+func (db *MemoryDB) RotateFeeEpoch(closed, opened *FeeEpoch) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	db.feeEpochs[closed.EpochStart] = *closed
+	db.feeEpochs[opened.EpochStart] = *opened
+	return nil
+}
+
+func (db *MemoryDB) GetCurrentFeeEpoch() (*FeeEpoch, bool, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	var current *FeeEpoch
+	for _, epoch := range db.feeEpochs {
+		if epoch.EpochEnd == 0 && (current == nil || epoch.EpochStart > current.EpochStart) {
+			epoch := epoch
+			current = &epoch
+		}
+	}
+	return current, current != nil, nil
+}
+
+func (db *MemoryDB) GetFeeHistory(limit int) ([]FeeEpoch, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	epochs := make([]FeeEpoch, 0, len(db.feeEpochs))
+	for _, epoch := range db.feeEpochs {
+		epochs = append(epochs, epoch)
+	}
+	slices.SortFunc(epochs, func(a, b FeeEpoch) int {
+		return cmp.Compare(b.EpochStart, a.EpochStart)
+	})
+	if len(epochs) > limit {
+		epochs = epochs[:limit]
+	}
+	return epochs, nil
+}
+
+func (db *MemoryDB) SetFeedsHidden(txID string, actionIndex *int, hidden bool, entry *ModerationEntry) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	found := false
+	for key, feed := range db.feeds {
+		if key.txID != txID || (actionIndex != nil && key.actionIndex != *actionIndex) {
+			continue
+		}
+		feed.Hidden = hidden
+		db.feeds[key] = feed
+		found = true
+	}
+	if !found {
+		return ErrNotFound
+	}
+	db.logModeration(entry)
+	return nil
+}
+
+func (db *MemoryDB) SetAuthorBanned(address string, banned bool, entry *ModerationEntry) error {
+	db.l.Lock()
+	defer db.l.Unlock()
+
+	if !banned {
+		delete(db.banned, address)
+	} else if _, ok := db.banned[address]; !ok {
+		db.banned[address] = entry.CreatedAt
+	}
+	db.logModeration(entry)
+	return nil
+}
+
+// logModeration appends [entry] to the moderation log. The caller must hold
+// the lock.
+func (db *MemoryDB) logModeration(entry *ModerationEntry) {
+	logged := *entry
+	logged.ID = int64(len(db.modLog) + 1)
+	db.modLog = append(db.modLog, logged)
+}
+
+func (db *MemoryDB) GetBannedAuthors() ([]string, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	addresses := make([]string, 0, len(db.banned))
+	for address := range db.banned {
+		addresses = append(addresses, address)
+	}
+	slices.Sort(addresses)
+	return addresses, nil
+}
+
+func (db *MemoryDB) GetModerationLog(limit int) ([]ModerationEntry, error) {
+	db.l.RLock()
+	defer db.l.RUnlock()
+
+	entries := make([]ModerationEntry, 0, min(limit, len(db.modLog)))
+	for i := len(db.modLog) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, db.modLog[i])
+	}
+	return entries, nil
+}
+
+func (*MemoryDB) Close() {}
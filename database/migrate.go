@@ -0,0 +1,253 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL dialects with their own set of migrations.
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// release than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is a numbered schema change. Migrations are applied in version
+// order and each one is reverted by its Down script.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt int64 // unix seconds, zero if pending
+}
+
+// loadMigrations reads the migrations for [dialect] from the embedded
+// migrations/<dialect> directory. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		version, err := strconv.Atoi(rawVersion)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", file)
+		}
+		script, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if len(migration.Up) == 0 || len(migration.Down) == 0 {
+			return nil, fmt.Errorf("migration %d is missing its up or down script", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrator applies and reverts the schema migrations of a SQL database. The
+// applied versions are recorded in the schema_migrations table.
+type Migrator struct {
+	conn       *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(conn *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		appliedAt BIGINT NOT NULL
+	)`
+	if _, err := conn.Exec(query); err != nil {
+		log.Printf("Error creating schema migrations table: %v", err)
+		return nil, err
+	}
+	return &Migrator{conn: conn, migrations: migrations}, nil
+}
+
+// Latest returns the version of the newest migration known to this release.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the version of the last applied migration, or zero if none
+// have been applied.
+func (m *Migrator) Version() (int, error) {
+	var version sql.NullInt64
+	query := `SELECT MAX(version) FROM schema_migrations`
+	if err := m.conn.QueryRow(query).Scan(&version); err != nil {
+		log.Printf("Error fetching schema version: %v", err)
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// CheckVersion returns ErrSchemaTooNew if the database has migrations applied
+// that this release does not know about.
+func (m *Migrator) CheckVersion() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, version, m.Latest())
+	}
+	return nil
+}
+
+// Status returns every known migration and when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.conn.Query(`SELECT version, appliedAt FROM schema_migrations`)
+	if err != nil {
+		log.Printf("Error fetching applied migrations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]int64{}
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{Migration: migration, AppliedAt: applied[migration.Version]})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns how many were
+// applied.
+func (m *Migrator) Up() (int, error) {
+	if err := m.CheckVersion(); err != nil {
+		return 0, err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, migration := range m.migrations[version:] {
+		log.Printf("Applying migration %d_%s", migration.Version, migration.Name)
+		err := m.apply(migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name, appliedAt) VALUES ($1, $2, $3)`, migration.Version, migration.Name, time.Now().Unix())
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Down reverts the last [steps] applied migrations.
+func (m *Migrator) Down(steps int) error {
+	if err := m.CheckVersion(); err != nil {
+		return err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	for ; steps > 0 && version > 0; steps-- {
+		migration := m.migrations[version-1]
+		log.Printf("Reverting migration %d_%s", migration.Version, migration.Name)
+		err := m.apply(migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		version--
+	}
+	return nil
+}
+
+// apply runs [script] and [record] in a single transaction.
+func (m *Migrator) apply(script string, record func(*sql.Tx) error) error {
+	tx, err := m.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
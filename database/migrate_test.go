@@ -0,0 +1,153 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openAtVersion returns a fresh database of [dialect] migrated up to
+// [version]. Postgres databases live in their own schema, dropped when the
+// test ends.
+func openAtVersion(t *testing.T, dialect string, version int) *sql.DB {
+	t.Helper()
+
+	var (
+		conn *sql.DB
+		err  error
+	)
+	switch dialect {
+	case DialectSQLite:
+		conn, err = OpenSQLite(filepath.Join(t.TempDir(), "feed.db"))
+		if err != nil {
+			t.Fatalf("opening SQLite: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+	case DialectPostgres:
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", postgresDSNEnv)
+		}
+		conn, err = sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("opening Postgres: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		// The search path is set per connection.
+		conn.SetMaxOpenConns(1)
+		schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+		if _, err := conn.Exec(`CREATE SCHEMA ` + schema); err != nil {
+			t.Fatalf("creating schema: %v", err)
+		}
+		t.Cleanup(func() { _, _ = conn.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+		if _, err := conn.Exec(`SET search_path TO ` + schema); err != nil {
+			t.Fatalf("selecting schema: %v", err)
+		}
+	}
+
+	migrator, err := NewMigrator(conn, dialect)
+	if err != nil {
+		t.Fatalf("creating migrator: %v", err)
+	}
+	migrator.migrations = migrator.migrations[:version]
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("migrating to version %d: %v", version, err)
+	}
+	return conn
+}
+
+// TestMigrateLegacyContent checks that 0004_search extracts the message and
+// URL of legacy posts, and leaves them empty when the content is not a JSON
+// object.
+func TestMigrateLegacyContent(t *testing.T) {
+	contents := []struct {
+		content sql.NullString
+		message string
+		url     string
+	}{
+		{sql.NullString{String: `{"message":"hello","url":"https://nukl.ai"}`, Valid: true}, "hello", "https://nukl.ai"},
+		{sql.NullString{String: `{"message":"no url"}`, Valid: true}, "no url", ""},
+		{sql.NullString{String: `not json`, Valid: true}, "", ""},
+		{sql.NullString{String: `{"message":`, Valid: true}, "", ""},
+		{sql.NullString{String: `[1, 2]`, Valid: true}, "", ""},
+		{sql.NullString{}, "", ""},
+	}
+	for _, dialect := range []string{DialectSQLite, DialectPostgres} {
+		t.Run(dialect, func(t *testing.T) {
+			conn := openAtVersion(t, dialect, 3)
+			for i, c := range contents {
+				_, err := conn.Exec(`INSERT INTO feeds (txid, subnetID, chainID, address, timestamp, fee, content) VALUES ($1, 'subnet', 'chain', 'author', $2, 0, $3)`,
+					fmt.Sprintf("tx%d", i), i, c.content)
+				if err != nil {
+					t.Fatalf("inserting legacy feed: %v", err)
+				}
+			}
+
+			db, err := newDB(conn, dialect)
+			if err != nil {
+				t.Fatalf("migrating legacy database: %v", err)
+			}
+			for i, c := range contents {
+				feed, err := db.GetFeed(fmt.Sprintf("tx%d", i), 0)
+				if err != nil {
+					t.Fatalf("reading migrated feed: %v", err)
+				}
+				if feed.Message != c.message || feed.URL != c.url {
+					t.Errorf("content %q migrated to message %q and URL %q, want %q and %q", c.content.String, feed.Message, feed.URL, c.message, c.url)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateChainBlocks checks that 0011_chain_blocks assigns existing
+// blocks and the cursor to the chain of the newest feed.
+func TestMigrateChainBlocks(t *testing.T) {
+	for _, dialect := range []string{DialectSQLite, DialectPostgres} {
+		t.Run(dialect, func(t *testing.T) {
+			conn := openAtVersion(t, dialect, 10)
+			statements := []string{
+				`INSERT INTO feeds (txid, subnetID, chainID, address, timestamp, fee) VALUES ('old', 'subnet', 'old-chain', 'author', 1, '0')`,
+				`INSERT INTO feeds (txid, subnetID, chainID, address, timestamp, fee) VALUES ('new', 'subnet', 'chain', 'author', 2, '0')`,
+				`INSERT INTO blocks (height, blockID, parentID, final) VALUES (1, 'block1', 'block0', TRUE)`,
+				`INSERT INTO blocks (height, blockID, parentID, final) VALUES (2, 'block2', 'block1', FALSE)`,
+				`INSERT INTO block_cursor (id, height) VALUES (1, 2)`,
+				`INSERT INTO reactions (txid, actionIndex, targetTxID, targetActionIndex, address, reaction, value, timestamp, height)
+					VALUES ('reaction', 0, 'old', 0, 'reactor', '+1', '0', 3, 2)`,
+			}
+			for _, statement := range statements {
+				if _, err := conn.Exec(statement); err != nil {
+					t.Fatalf("inserting legacy rows: %v", err)
+				}
+			}
+
+			db, err := newDB(conn, dialect)
+			if err != nil {
+				t.Fatalf("migrating legacy database: %v", err)
+			}
+			if cursor, ok, err := db.GetBlockCursor("chain"); err != nil || !ok || cursor != 2 {
+				t.Fatalf("cursor = %d (%t, %v), want 2", cursor, ok, err)
+			}
+			for height := uint64(1); height <= 2; height++ {
+				if _, ok, err := db.GetBlock("chain", height); err != nil || !ok {
+					t.Fatalf("block %d was not assigned to the chain (%v)", height, err)
+				}
+			}
+
+			// Rolling back the other chain keeps the reaction to its post.
+			if err := db.RollbackFrom("chain", 1); err != nil {
+				t.Fatal(err)
+			}
+			totals, err := db.GetReactionTotals([]string{"old"})
+			if err != nil || len(totals) != 1 {
+				t.Fatalf("reactions to the old chain = %+v (%v), want 1", totals, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS fee_epochs;
+DROP TABLE IF EXISTS blocks;
+DROP TABLE IF EXISTS block_cursor;
+DROP TABLE IF EXISTS feeds;
@@ -0,0 +1,40 @@
+-- Tables created by releases before versioned migrations already exist, so
+-- every statement in the baseline is idempotent.
+CREATE TABLE IF NOT EXISTS feeds (
+	txid TEXT PRIMARY KEY,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee BIGINT,
+	content TEXT
+);
+
+-- Feeds stored before block tracking was introduced are treated as final.
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS blockID TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS height BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'final';
+
+CREATE INDEX IF NOT EXISTS feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX IF NOT EXISTS feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
+
+-- block_cursor holds a single row tracking the last block that was fully
+-- processed, so ingestion can resume from there after a restart.
+CREATE TABLE IF NOT EXISTS block_cursor (
+	id INTEGER PRIMARY KEY,
+	height BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS blocks (
+	height BIGINT PRIMARY KEY,
+	blockID TEXT NOT NULL,
+	parentID TEXT NOT NULL,
+	final BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS fee_epochs (
+	epochStart BIGINT PRIMARY KEY,
+	epochEnd BIGINT,
+	fee BIGINT NOT NULL,
+	messages INTEGER NOT NULL DEFAULT 0
+);
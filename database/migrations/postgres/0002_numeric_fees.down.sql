@@ -0,0 +1,3 @@
+-- Fails if any fee no longer fits in a BIGINT.
+ALTER TABLE fee_epochs ALTER COLUMN fee TYPE BIGINT;
+ALTER TABLE feeds ALTER COLUMN fee TYPE BIGINT;
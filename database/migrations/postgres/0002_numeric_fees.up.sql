@@ -0,0 +1,3 @@
+-- BIGINT is signed, so fees above 2^63-1 base units could not be stored.
+ALTER TABLE feeds ALTER COLUMN fee TYPE NUMERIC(20,0);
+ALTER TABLE fee_epochs ALTER COLUMN fee TYPE NUMERIC(20,0);
@@ -0,0 +1,5 @@
+-- Only the first post of each transaction is kept.
+DELETE FROM feeds WHERE actionIndex <> (SELECT MIN(f.actionIndex) FROM feeds f WHERE f.txid = feeds.txid);
+ALTER TABLE feeds DROP CONSTRAINT feeds_pkey;
+ALTER TABLE feeds ADD PRIMARY KEY (txid);
+ALTER TABLE feeds DROP COLUMN actionIndex;
@@ -0,0 +1,5 @@
+-- A transaction can contain several transfers to the feed, so posts are keyed
+-- by transaction and action index.
+ALTER TABLE feeds ADD COLUMN actionIndex INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds DROP CONSTRAINT feeds_pkey;
+ALTER TABLE feeds ADD PRIMARY KEY (txid, actionIndex);
@@ -0,0 +1,4 @@
+DROP INDEX feeds_search_idx;
+ALTER TABLE feeds DROP COLUMN searchVector;
+ALTER TABLE feeds DROP COLUMN url;
+ALTER TABLE feeds DROP COLUMN message;
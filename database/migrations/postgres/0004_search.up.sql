@@ -0,0 +1,21 @@
+-- The message and URL are extracted from the JSON content so posts can be
+-- searched. searchVector backs full-text search on the message.
+ALTER TABLE feeds ADD COLUMN message TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN url TEXT NOT NULL DEFAULT '';
+
+-- Legacy content that is not valid JSON is left with an empty message and
+-- URL, like on SQLite, instead of failing the cast.
+CREATE FUNCTION feeds_content_jsonb(content TEXT) RETURNS jsonb AS $$
+BEGIN
+	RETURN content::jsonb;
+EXCEPTION WHEN others THEN
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+UPDATE feeds SET
+	message = COALESCE(feeds_content_jsonb(content)->>'message', ''),
+	url = COALESCE(feeds_content_jsonb(content)->>'url', '');
+DROP FUNCTION feeds_content_jsonb(TEXT);
+
+ALTER TABLE feeds ADD COLUMN searchVector tsvector GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+CREATE INDEX feeds_search_idx ON feeds USING GIN (searchVector);
@@ -0,0 +1,5 @@
+DROP TRIGGER moderation_log_append_only ON moderation_log;
+DROP FUNCTION moderation_log_append_only();
+DROP TABLE moderation_log;
+DROP TABLE banned_authors;
+ALTER TABLE feeds DROP COLUMN hidden;
@@ -0,0 +1,27 @@
+-- Hidden posts are kept but never served.
+ALTER TABLE feeds ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Posts by banned authors are indexed as hidden.
+CREATE TABLE banned_authors (
+	address TEXT PRIMARY KEY,
+	bannedAt BIGINT NOT NULL
+);
+
+-- moderation_log is an append-only audit trail of moderation actions.
+CREATE TABLE moderation_log (
+	id BIGSERIAL PRIMARY KEY,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL,
+	moderator TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	createdAt BIGINT NOT NULL
+);
+
+CREATE FUNCTION moderation_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'moderation_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER moderation_log_append_only BEFORE UPDATE OR DELETE ON moderation_log
+	FOR EACH ROW EXECUTE FUNCTION moderation_log_append_only();
@@ -0,0 +1,8 @@
+-- Replies reference the post they answer and the first post of their
+-- thread. Posts that are not replies have an empty parentTxID and rootTxID.
+ALTER TABLE feeds ADD COLUMN parentTxID TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN parentActionIndex INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN rootTxID TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN rootActionIndex INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX feeds_parent_idx ON feeds (parentTxID, parentActionIndex);
+CREATE INDEX feeds_root_idx ON feeds (rootTxID, rootActionIndex, timestamp);
@@ -0,0 +1,16 @@
+-- Reactions are transfers to the feed that react to a post instead of
+-- making one. The value of the transfer tips the post.
+CREATE TABLE reactions (
+	txid TEXT NOT NULL,
+	actionIndex INTEGER NOT NULL,
+	targetTxID TEXT NOT NULL,
+	targetActionIndex INTEGER NOT NULL,
+	address TEXT NOT NULL,
+	reaction TEXT NOT NULL,
+	value NUMERIC(20,0) NOT NULL,
+	timestamp BIGINT NOT NULL,
+	height BIGINT NOT NULL,
+	PRIMARY KEY (txid, actionIndex)
+);
+CREATE INDEX reactions_target_idx ON reactions (targetTxID, targetActionIndex);
+CREATE INDEX reactions_height_idx ON reactions (height);
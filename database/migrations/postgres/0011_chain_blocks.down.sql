@@ -0,0 +1,20 @@
+-- Only the blocks and cursor of the chain indexed last are kept.
+DROP INDEX feed_revisions_height_idx;
+ALTER TABLE feed_revisions DROP COLUMN chainID;
+CREATE INDEX feed_revisions_height_idx ON feed_revisions (height);
+
+DROP INDEX reactions_height_idx;
+ALTER TABLE reactions DROP COLUMN chainID;
+CREATE INDEX reactions_height_idx ON reactions (height);
+
+DELETE FROM blocks WHERE chainID <> (SELECT chainID FROM block_cursor ORDER BY height DESC LIMIT 1);
+ALTER TABLE blocks DROP CONSTRAINT blocks_pkey;
+ALTER TABLE blocks DROP COLUMN chainID;
+ALTER TABLE blocks ADD PRIMARY KEY (height);
+
+DELETE FROM block_cursor WHERE chainID <> (SELECT chainID FROM block_cursor ORDER BY height DESC LIMIT 1);
+ALTER TABLE block_cursor DROP CONSTRAINT block_cursor_pkey;
+ALTER TABLE block_cursor DROP COLUMN chainID;
+ALTER TABLE block_cursor ADD COLUMN id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE block_cursor ALTER COLUMN id DROP DEFAULT;
+ALTER TABLE block_cursor ADD PRIMARY KEY (id);
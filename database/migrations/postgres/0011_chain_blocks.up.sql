@@ -0,0 +1,29 @@
+-- Blocks, the block cursor, reactions and revisions are kept per chain, so
+-- that after switching to another chain the blocks indexed on the previous
+-- one are neither rolled back nor mistaken for conflicts. Existing blocks and
+-- the cursor belong to the chain of the newest feed; reactions and revisions
+-- to the chain of the feed they target.
+ALTER TABLE blocks ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE blocks SET chainID = COALESCE((SELECT chainID FROM feeds ORDER BY timestamp DESC LIMIT 1), '');
+ALTER TABLE blocks DROP CONSTRAINT blocks_pkey;
+ALTER TABLE blocks ADD PRIMARY KEY (chainID, height);
+ALTER TABLE blocks ALTER COLUMN chainID DROP DEFAULT;
+
+ALTER TABLE block_cursor ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE block_cursor SET chainID = COALESCE((SELECT chainID FROM feeds ORDER BY timestamp DESC LIMIT 1), '');
+ALTER TABLE block_cursor DROP CONSTRAINT block_cursor_pkey;
+ALTER TABLE block_cursor DROP COLUMN id;
+ALTER TABLE block_cursor ADD PRIMARY KEY (chainID);
+ALTER TABLE block_cursor ALTER COLUMN chainID DROP DEFAULT;
+
+ALTER TABLE reactions ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE reactions r SET chainID = COALESCE(f.chainID, '') FROM feeds f
+	WHERE f.txid = r.targetTxID AND f.actionIndex = r.targetActionIndex;
+DROP INDEX reactions_height_idx;
+CREATE INDEX reactions_height_idx ON reactions (chainID, height);
+
+ALTER TABLE feed_revisions ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE feed_revisions r SET chainID = COALESCE(f.chainID, '') FROM feeds f
+	WHERE f.txid = r.txid AND f.actionIndex = r.actionIndex;
+DROP INDEX feed_revisions_height_idx;
+CREATE INDEX feed_revisions_height_idx ON feed_revisions (chainID, height);
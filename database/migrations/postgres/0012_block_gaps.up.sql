@@ -0,0 +1,9 @@
+-- Heights whose blocks could not be fetched are recorded as gaps, so that
+-- they are filled in later instead of being skipped for good. Gaps of a chain
+-- never overlap.
+CREATE TABLE block_gaps (
+	chainID TEXT NOT NULL,
+	fromHeight BIGINT NOT NULL,
+	toHeight BIGINT NOT NULL,
+	PRIMARY KEY (chainID, fromHeight)
+);
@@ -0,0 +1 @@
+ALTER TABLE moderation_log DROP COLUMN moderatorName;
@@ -0,0 +1,2 @@
+ALTER TABLE blocks DROP COLUMN epochStart;
+ALTER TABLE blocks DROP COLUMN messages;
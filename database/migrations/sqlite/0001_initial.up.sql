@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS feeds (
+	txid TEXT PRIMARY KEY,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee BIGINT,
+	content TEXT,
+	blockID TEXT NOT NULL DEFAULT '',
+	height BIGINT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'final'
+);
+
+CREATE INDEX IF NOT EXISTS feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX IF NOT EXISTS feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
+
+CREATE TABLE IF NOT EXISTS block_cursor (
+	id INTEGER PRIMARY KEY,
+	height BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS blocks (
+	height BIGINT PRIMARY KEY,
+	blockID TEXT NOT NULL,
+	parentID TEXT NOT NULL,
+	final BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS fee_epochs (
+	epochStart BIGINT PRIMARY KEY,
+	epochEnd BIGINT,
+	fee BIGINT NOT NULL,
+	messages INTEGER NOT NULL DEFAULT 0
+);
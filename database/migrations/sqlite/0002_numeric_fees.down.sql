@@ -0,0 +1,28 @@
+-- Fees above 2^63-1 are clamped to the largest INTEGER by the cast.
+CREATE TABLE feeds_old (
+	txid TEXT PRIMARY KEY,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee BIGINT,
+	content TEXT,
+	blockID TEXT NOT NULL DEFAULT '',
+	height BIGINT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'final'
+);
+INSERT INTO feeds_old SELECT txid, subnetID, chainID, address, timestamp, CAST(fee AS INTEGER), content, blockID, height, status FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_old RENAME TO feeds;
+CREATE INDEX feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
+
+CREATE TABLE fee_epochs_old (
+	epochStart BIGINT PRIMARY KEY,
+	epochEnd BIGINT,
+	fee BIGINT NOT NULL,
+	messages INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO fee_epochs_old SELECT epochStart, epochEnd, CAST(fee AS INTEGER), messages FROM fee_epochs;
+DROP TABLE fee_epochs;
+ALTER TABLE fee_epochs_old RENAME TO fee_epochs;
@@ -0,0 +1,30 @@
+-- SQLite integers are signed 64-bit and NUMERIC falls back to REAL for larger
+-- values, so fees are stored as decimal strings instead. Column types cannot
+-- be altered in SQLite, so both tables are rebuilt.
+CREATE TABLE feeds_new (
+	txid TEXT PRIMARY KEY,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee TEXT,
+	content TEXT,
+	blockID TEXT NOT NULL DEFAULT '',
+	height BIGINT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'final'
+);
+INSERT INTO feeds_new SELECT txid, subnetID, chainID, address, timestamp, CAST(fee AS TEXT), content, blockID, height, status FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_new RENAME TO feeds;
+CREATE INDEX feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
+
+CREATE TABLE fee_epochs_new (
+	epochStart BIGINT PRIMARY KEY,
+	epochEnd BIGINT,
+	fee TEXT NOT NULL,
+	messages INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO fee_epochs_new SELECT epochStart, epochEnd, CAST(fee AS TEXT), messages FROM fee_epochs;
+DROP TABLE fee_epochs;
+ALTER TABLE fee_epochs_new RENAME TO fee_epochs;
@@ -0,0 +1,20 @@
+-- Only the first post of each transaction is kept.
+CREATE TABLE feeds_old (
+	txid TEXT PRIMARY KEY,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee TEXT,
+	content TEXT,
+	blockID TEXT NOT NULL DEFAULT '',
+	height BIGINT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'final'
+);
+INSERT INTO feeds_old
+	SELECT txid, subnetID, chainID, address, timestamp, fee, content, blockID, height, status FROM feeds f
+	WHERE actionIndex = (SELECT MIN(actionIndex) FROM feeds WHERE txid = f.txid);
+DROP TABLE feeds;
+ALTER TABLE feeds_old RENAME TO feeds;
+CREATE INDEX feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
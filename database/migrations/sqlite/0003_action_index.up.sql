@@ -0,0 +1,23 @@
+-- A transaction can contain several transfers to the feed, so posts are keyed
+-- by transaction and action index. SQLite cannot change a primary key in
+-- place, so the table is rebuilt.
+CREATE TABLE feeds_new (
+	txid TEXT NOT NULL,
+	actionIndex INTEGER NOT NULL DEFAULT 0,
+	subnetID TEXT,
+	chainID TEXT,
+	address TEXT,
+	timestamp BIGINT,
+	fee TEXT,
+	content TEXT,
+	blockID TEXT NOT NULL DEFAULT '',
+	height BIGINT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'final',
+	PRIMARY KEY (txid, actionIndex)
+);
+INSERT INTO feeds_new (txid, subnetID, chainID, address, timestamp, fee, content, blockID, height, status)
+	SELECT txid, subnetID, chainID, address, timestamp, fee, content, blockID, height, status FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_new RENAME TO feeds;
+CREATE INDEX feeds_chain_timestamp_idx ON feeds (subnetID, chainID, timestamp DESC, txid DESC);
+CREATE INDEX feeds_address_timestamp_idx ON feeds (address, timestamp DESC, txid DESC);
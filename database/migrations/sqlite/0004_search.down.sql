@@ -0,0 +1,2 @@
+ALTER TABLE feeds DROP COLUMN url;
+ALTER TABLE feeds DROP COLUMN message;
@@ -0,0 +1,8 @@
+-- The message and URL are extracted from the JSON content so posts can be
+-- searched. SQLite has no full-text index here; searches scan the message.
+ALTER TABLE feeds ADD COLUMN message TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN url TEXT NOT NULL DEFAULT '';
+UPDATE feeds SET
+	message = COALESCE(json_extract(content, '$.message'), ''),
+	url = COALESCE(json_extract(content, '$.url'), '')
+	WHERE json_valid(content);
@@ -0,0 +1,2 @@
+ALTER TABLE feeds ADD COLUMN content TEXT;
+UPDATE feeds SET content = json_object('message', message, 'url', url);
@@ -0,0 +1,2 @@
+-- Post content is stored in typed columns, so the JSON copy is dropped.
+ALTER TABLE feeds DROP COLUMN content;
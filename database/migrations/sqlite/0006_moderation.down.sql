@@ -0,0 +1,5 @@
+DROP TRIGGER moderation_log_no_delete;
+DROP TRIGGER moderation_log_no_update;
+DROP TABLE moderation_log;
+DROP TABLE banned_authors;
+ALTER TABLE feeds DROP COLUMN hidden;
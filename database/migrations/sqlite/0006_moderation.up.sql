@@ -0,0 +1,28 @@
+-- Hidden posts are kept but never served.
+ALTER TABLE feeds ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Posts by banned authors are indexed as hidden.
+CREATE TABLE banned_authors (
+	address TEXT PRIMARY KEY,
+	bannedAt BIGINT NOT NULL
+);
+
+-- moderation_log is an append-only audit trail of moderation actions.
+CREATE TABLE moderation_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL,
+	moderator TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	createdAt BIGINT NOT NULL
+);
+
+CREATE TRIGGER moderation_log_no_update BEFORE UPDATE ON moderation_log
+BEGIN
+	SELECT RAISE(ABORT, 'moderation_log is append-only');
+END;
+
+CREATE TRIGGER moderation_log_no_delete BEFORE DELETE ON moderation_log
+BEGIN
+	SELECT RAISE(ABORT, 'moderation_log is append-only');
+END;
@@ -0,0 +1,2 @@
+-- Posts record the decoder of their memo. Earlier posts were all JSON.
+ALTER TABLE feeds ADD COLUMN encoding TEXT NOT NULL DEFAULT 'json';
@@ -0,0 +1,6 @@
+DROP INDEX feeds_root_idx;
+DROP INDEX feeds_parent_idx;
+ALTER TABLE feeds DROP COLUMN rootActionIndex;
+ALTER TABLE feeds DROP COLUMN rootTxID;
+ALTER TABLE feeds DROP COLUMN parentActionIndex;
+ALTER TABLE feeds DROP COLUMN parentTxID;
@@ -0,0 +1,3 @@
+DROP TABLE feed_revisions;
+ALTER TABLE feeds DROP COLUMN deleted;
+ALTER TABLE feeds DROP COLUMN revision;
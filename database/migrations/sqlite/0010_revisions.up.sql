@@ -0,0 +1,20 @@
+-- Authors can edit and delete their posts. feeds holds the latest version
+-- and its revision number, and feed_revisions every version: the original
+-- post as revision 0, followed by each edit or deletion.
+ALTER TABLE feeds ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE feed_revisions (
+	txid TEXT NOT NULL,
+	actionIndex INTEGER NOT NULL,
+	revision INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	revisionTxID TEXT NOT NULL,
+	revisionActionIndex INTEGER NOT NULL,
+	message TEXT NOT NULL,
+	url TEXT NOT NULL,
+	encoding TEXT NOT NULL,
+	timestamp BIGINT NOT NULL,
+	height BIGINT NOT NULL,
+	PRIMARY KEY (txid, actionIndex, revision)
+);
+CREATE INDEX feed_revisions_height_idx ON feed_revisions (height);
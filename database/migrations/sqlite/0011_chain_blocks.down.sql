@@ -0,0 +1,29 @@
+-- Only the blocks and cursor of the chain indexed last are kept.
+DROP INDEX feed_revisions_height_idx;
+ALTER TABLE feed_revisions DROP COLUMN chainID;
+CREATE INDEX feed_revisions_height_idx ON feed_revisions (height);
+
+DROP INDEX reactions_height_idx;
+ALTER TABLE reactions DROP COLUMN chainID;
+CREATE INDEX reactions_height_idx ON reactions (height);
+
+CREATE TABLE blocks_old (
+	height BIGINT PRIMARY KEY,
+	blockID TEXT NOT NULL,
+	parentID TEXT NOT NULL,
+	final BOOLEAN NOT NULL DEFAULT FALSE
+);
+INSERT INTO blocks_old (height, blockID, parentID, final)
+	SELECT height, blockID, parentID, final FROM blocks
+	WHERE chainID = (SELECT chainID FROM block_cursor ORDER BY height DESC LIMIT 1);
+DROP TABLE blocks;
+ALTER TABLE blocks_old RENAME TO blocks;
+
+CREATE TABLE block_cursor_old (
+	id INTEGER PRIMARY KEY,
+	height BIGINT NOT NULL
+);
+INSERT INTO block_cursor_old (id, height)
+	SELECT 1, height FROM block_cursor ORDER BY height DESC LIMIT 1;
+DROP TABLE block_cursor;
+ALTER TABLE block_cursor_old RENAME TO block_cursor;
@@ -0,0 +1,39 @@
+-- Blocks, the block cursor, reactions and revisions are kept per chain, so
+-- that after switching to another chain the blocks indexed on the previous
+-- one are neither rolled back nor mistaken for conflicts. Existing blocks and
+-- the cursor belong to the chain of the newest feed; reactions and revisions
+-- to the chain of the feed they target. SQLite cannot change a primary key
+-- in place, so blocks and block_cursor are rebuilt.
+CREATE TABLE blocks_new (
+	chainID TEXT NOT NULL,
+	height BIGINT NOT NULL,
+	blockID TEXT NOT NULL,
+	parentID TEXT NOT NULL,
+	final BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (chainID, height)
+);
+INSERT INTO blocks_new (chainID, height, blockID, parentID, final)
+	SELECT COALESCE((SELECT chainID FROM feeds ORDER BY timestamp DESC LIMIT 1), ''), height, blockID, parentID, final FROM blocks;
+DROP TABLE blocks;
+ALTER TABLE blocks_new RENAME TO blocks;
+
+CREATE TABLE block_cursor_new (
+	chainID TEXT PRIMARY KEY,
+	height BIGINT NOT NULL
+);
+INSERT INTO block_cursor_new (chainID, height)
+	SELECT COALESCE((SELECT chainID FROM feeds ORDER BY timestamp DESC LIMIT 1), ''), height FROM block_cursor;
+DROP TABLE block_cursor;
+ALTER TABLE block_cursor_new RENAME TO block_cursor;
+
+ALTER TABLE reactions ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE reactions SET chainID = COALESCE((SELECT f.chainID FROM feeds f
+	WHERE f.txid = reactions.targetTxID AND f.actionIndex = reactions.targetActionIndex), '');
+DROP INDEX reactions_height_idx;
+CREATE INDEX reactions_height_idx ON reactions (chainID, height);
+
+ALTER TABLE feed_revisions ADD COLUMN chainID TEXT NOT NULL DEFAULT '';
+UPDATE feed_revisions SET chainID = COALESCE((SELECT f.chainID FROM feeds f
+	WHERE f.txid = feed_revisions.txid AND f.actionIndex = feed_revisions.actionIndex), '');
+DROP INDEX feed_revisions_height_idx;
+CREATE INDEX feed_revisions_height_idx ON feed_revisions (chainID, height);
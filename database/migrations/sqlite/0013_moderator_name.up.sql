@@ -0,0 +1,3 @@
+-- moderator is the verified identity of the admin who took an action, and
+-- moderatorName the display name they supplied, if any.
+ALTER TABLE moderation_log ADD COLUMN moderatorName TEXT NOT NULL DEFAULT '';
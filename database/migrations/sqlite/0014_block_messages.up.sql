@@ -0,0 +1,5 @@
+-- Blocks record how many paid messages they counted towards the fee epoch
+-- starting at epochStart, so that rolling them back can take the messages out
+-- of the running epoch.
+ALTER TABLE blocks ADD COLUMN messages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE blocks ADD COLUMN epochStart BIGINT NOT NULL DEFAULT 0;
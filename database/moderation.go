@@ -0,0 +1,142 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// Moderation actions recorded in the moderation log.
+const (
+	ModerationHide   = "hide"
+	ModerationUnhide = "unhide"
+	ModerationBan    = "ban"
+	ModerationUnban  = "unban"
+)
+
+// ModerationEntry is a record of the append-only moderation log. Moderator
+// is the verified identity of the admin who took the action and
+// ModeratorName the display name they supplied, if any.
+type ModerationEntry struct {
+	ID            int64  `json:"id"`
+	Action        string `json:"action"`
+	Target        string `json:"target"`
+	Moderator     string `json:"moderator"`
+	ModeratorName string `json:"moderatorName"`
+	Reason        string `json:"reason"`
+	CreatedAt     int64  `json:"createdAt"`
+}
+
+const moderationColumns = `id, action, target, moderator, moderatorName, reason, createdAt`
+
+func logModeration(ex execer, entry *ModerationEntry) error {
+	query := `INSERT INTO moderation_log (action, target, moderator, moderatorName, reason, createdAt) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := ex.Exec(query, entry.Action, entry.Target, entry.Moderator, entry.ModeratorName, entry.Reason, entry.CreatedAt)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+	}
+	return err
+}
+
+// SetFeedsHidden hides or unhides the feeds posted in [txID], or only the one
+// posted by action [actionIndex] if it is set, and records [entry] in the
+// moderation log. ErrNotFound is returned if no feed matches.
+func (db *DB) SetFeedsHidden(txID string, actionIndex *int, hidden bool, entry *ModerationEntry) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting moderation: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var result sql.Result
+	if actionIndex != nil {
+		result, err = tx.Exec(`UPDATE feeds SET hidden = $1 WHERE txid = $2 AND actionIndex = $3`, hidden, txID, *actionIndex)
+	} else {
+		result, err = tx.Exec(`UPDATE feeds SET hidden = $1 WHERE txid = $2`, hidden, txID)
+	}
+	if err != nil {
+		log.Printf("Error hiding feeds: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	if err := logModeration(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetAuthorBanned bans or unbans [address] and records [entry] in the
+// moderation log. Banning is idempotent, as is unbanning.
+func (db *DB) SetAuthorBanned(address string, banned bool, entry *ModerationEntry) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting moderation: %v", err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if banned {
+		_, err = tx.Exec(`INSERT INTO banned_authors (address, bannedAt) VALUES ($1, $2) ON CONFLICT (address) DO NOTHING`, address, entry.CreatedAt)
+	} else {
+		_, err = tx.Exec(`DELETE FROM banned_authors WHERE address = $1`, address)
+	}
+	if err != nil {
+		log.Printf("Error updating banned authors: %v", err)
+		return err
+	}
+	if err := logModeration(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBannedAuthors returns the addresses of every banned author.
+func (db *DB) GetBannedAuthors() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT address FROM banned_authors ORDER BY address`)
+	if err != nil {
+		log.Printf("Error fetching banned authors: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			log.Printf("Error scanning banned author row: %v", err)
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// GetModerationLog returns the [limit] most recent moderation actions, newest
+// first.
+func (db *DB) GetModerationLog(limit int) ([]ModerationEntry, error) {
+	query := `SELECT ` + moderationColumns + ` FROM moderation_log ORDER BY id DESC LIMIT $1`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		log.Printf("Error fetching moderation log: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ModerationEntry
+	for rows.Next() {
+		var entry ModerationEntry
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.Target, &entry.Moderator, &entry.ModeratorName, &entry.Reason, &entry.CreatedAt); err != nil {
+			log.Printf("Error scanning moderation log row: %v", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
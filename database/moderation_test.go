@@ -0,0 +1,51 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"testing"
+)
+
+func TestModerationLogAppendOnly(t *testing.T) {
+	for name, store := range testStores(t) {
+		db, ok := store.(*DB)
+		if !ok {
+			// The log is only enforced as append-only by the SQL triggers.
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			address := uniqueID("author")
+			entry := &ModerationEntry{Action: ModerationBan, Target: address, Moderator: "admin", Reason: "spam", CreatedAt: 1}
+			if err := db.SetAuthorBanned(address, true, entry); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := db.GetModerationLog(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Target != address {
+				t.Fatalf("moderation log = %+v, want the ban of %s", entries, address)
+			}
+			logged := entries[0]
+
+			if _, err := db.conn.Exec(`UPDATE moderation_log SET reason = $1 WHERE id = $2`, "edited", logged.ID); err == nil {
+				t.Fatal("updated the moderation log")
+			}
+			if _, err := db.conn.Exec(`DELETE FROM moderation_log WHERE id = $1`, logged.ID); err == nil {
+				t.Fatal("deleted from the moderation log")
+			}
+			if _, err := db.conn.Exec(`DELETE FROM moderation_log`); err == nil {
+				t.Fatal("cleared the moderation log")
+			}
+
+			entries, err = db.GetModerationLog(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0] != logged {
+				t.Fatalf("moderation log = %+v, want %+v unchanged", entries, logged)
+			}
+		})
+	}
+}
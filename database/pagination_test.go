@@ -0,0 +1,133 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// pageKey is the position of a feed in the feed ordering.
+type pageKey struct {
+	timestamp   int64
+	txID        string
+	actionIndex int
+}
+
+func (k pageKey) cursor(newer bool) *PageCursor {
+	return &PageCursor{Timestamp: k.timestamp, TxID: k.txID, ActionIndex: k.actionIndex, Newer: newer}
+}
+
+// indexSameTimestamp indexes several posts per transaction by [address] on
+// [chainID], nearly all in the same block and so at the same timestamp, in a
+// random order. It returns their positions newest first.
+func indexSameTimestamp(t *testing.T, db Store, chainID, address string) []pageKey {
+	t.Helper()
+
+	prefix := uniqueID("tx")
+	var feeds []FeedObject
+	for tx := 0; tx < 10; tx++ {
+		for action := 0; action < 3; action++ {
+			feeds = append(feeds, FeedObject{TxID: fmt.Sprintf("%s-%02d", prefix, tx), ActionIndex: action, Timestamp: 1000, Height: 1})
+		}
+	}
+	// A post on each side of the shared timestamp.
+	feeds = append(feeds,
+		FeedObject{TxID: prefix + "-older", Timestamp: 999, Height: 1},
+		FeedObject{TxID: prefix + "-newer", Timestamp: 1001, Height: 1},
+	)
+	rand.Shuffle(len(feeds), func(i, j int) { feeds[i], feeds[j] = feeds[j], feeds[i] })
+
+	keys := make([]pageKey, 0, len(feeds))
+	for i := range feeds {
+		feed := &feeds[i]
+		feed.SubnetID, feed.ChainID, feed.Address = "subnet", chainID, address
+		feed.Message, feed.Status = "post", StatusFinal
+		keys = append(keys, pageKey{feed.Timestamp, feed.TxID, feed.ActionIndex})
+	}
+	if err := db.IndexBlock(&BlockObject{ChainID: chainID, Height: 1, BlockID: uniqueID("block")}, feeds, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(keys, func(a, b pageKey) int {
+		return -compareFeed(&FeedObject{Timestamp: a.timestamp, TxID: a.txID, ActionIndex: a.actionIndex}, b.cursor(false))
+	})
+	return keys
+}
+
+// checkPages pages through the feeds returned by [get], [limit] at a time,
+// older then newer, and checks that the pages neither overlap nor skip any
+// of [want], newest first.
+func checkPages(t *testing.T, want []pageKey, limit int, get func(cursor *PageCursor, limit int) ([]FeedObject, error)) {
+	t.Helper()
+
+	page := func(cursor *PageCursor) []pageKey {
+		t.Helper()
+
+		feeds, err := get(cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feeds) > limit {
+			t.Fatalf("got %d feeds, want at most %d", len(feeds), limit)
+		}
+		keys := make([]pageKey, 0, len(feeds))
+		for _, feed := range feeds {
+			keys = append(keys, pageKey{feed.Timestamp, feed.TxID, feed.ActionIndex})
+		}
+		return keys
+	}
+
+	// Older pages, starting from the newest feed.
+	got := page(nil)
+	for cursor := got; len(cursor) > 0; {
+		cursor = page(got[len(got)-1].cursor(false))
+		got = append(got, cursor...)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("older pages = %v, want %v", got, want)
+	}
+
+	// Newer pages, starting next to the oldest feed.
+	oldest := want[len(want)-1]
+	got = page(oldest.cursor(true))
+	for cursor := got; len(cursor) > 0; {
+		cursor = page(got[0].cursor(true))
+		got = append(cursor, got...)
+	}
+	if !slices.Equal(got, want[:len(want)-1]) {
+		t.Fatalf("newer pages = %v, want %v", got, want[:len(want)-1])
+	}
+}
+
+func TestFeedPagesSameTimestamp(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			chainID := uniqueID("chain")
+			want := indexSameTimestamp(t, db, chainID, "author")
+			for _, limit := range []int{1, 4, 7, len(want)} {
+				t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+					checkPages(t, want, limit, func(cursor *PageCursor, limit int) ([]FeedObject, error) {
+						return db.GetLastFeeds("subnet", chainID, cursor, limit)
+					})
+				})
+			}
+		})
+	}
+}
+
+func TestFeedPagesByAddress(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			chainID := uniqueID("chain")
+			want := indexSameTimestamp(t, db, chainID, "author")
+			// Posts by another author at the same timestamps are left out.
+			indexSameTimestamp(t, db, chainID, "other")
+			checkPages(t, want, 4, func(cursor *PageCursor, limit int) ([]FeedObject, error) {
+				return db.GetFeedsByUser("subnet", chainID, "author", cursor, limit)
+			})
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// NewPostgresDB creates a Store using the Postgres connection [conn],
+// applying any pending migrations.
+func NewPostgresDB(conn *sql.DB) (*DB, error) {
+	return newDB(conn, DialectPostgres)
+}
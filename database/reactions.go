@@ -0,0 +1,115 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ReactionObject is a reaction to a feed, made by a transfer to the feed.
+// Value is the amount transferred, which tips the feed.
+type ReactionObject struct {
+	ChainID           string `json:"chainID"`
+	TxID              string `json:"txID"`
+	ActionIndex       int    `json:"actionIndex"`
+	TargetTxID        string `json:"targetTxID"`
+	TargetActionIndex int    `json:"targetActionIndex"`
+	Address           string `json:"address"`
+	Reaction          string `json:"reaction"`
+	Value             uint64 `json:"value"`
+	Timestamp         int64  `json:"timestamp"`
+	Height            uint64 `json:"height"`
+}
+
+// ReactionTotal counts the reactions of one kind to a feed and sums their
+// value.
+type ReactionTotal struct {
+	TargetTxID        string `json:"targetTxID"`
+	TargetActionIndex int    `json:"targetActionIndex"`
+	Reaction          string `json:"reaction"`
+	Count             int    `json:"count"`
+	Tips              uint64 `json:"tips"`
+}
+
+// AuthorStats counts the visible feeds of an author that were not deleted
+// and the reactions to all their visible feeds, and sums the tips they
+// received.
+type AuthorStats struct {
+	Posts     int    `json:"posts"`
+	Reactions int    `json:"reactions"`
+	Tips      uint64 `json:"tips"`
+}
+
+func saveReaction(ex execer, reaction *ReactionObject) error {
+	query := `INSERT INTO reactions (chainID, txid, actionIndex, targetTxID, targetActionIndex, address, reaction, value, timestamp, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (txid, actionIndex) DO NOTHING`
+	_, err := ex.Exec(query, reaction.ChainID, reaction.TxID, reaction.ActionIndex, reaction.TargetTxID, reaction.TargetActionIndex, reaction.Address, reaction.Reaction, amount(reaction.Value), reaction.Timestamp, reaction.Height)
+	if err != nil {
+		log.Printf("Error saving reaction: %v", err)
+	}
+	return err
+}
+
+// GetReactionTotals returns the reaction totals of every feed posted in
+// [txIDs]. Tips are capped at math.MaxUint64.
+func (db *DB) GetReactionTotals(txIDs []string) ([]ReactionTotal, error) {
+	if len(txIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(txIDs))
+	args := make([]any, len(txIDs))
+	for i, txID := range txIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = txID
+	}
+	query := `SELECT targetTxID, targetActionIndex, reaction, COUNT(*), ` + db.sumAmounts("value") + ` FROM reactions
+		WHERE targetTxID IN (` + strings.Join(placeholders, ", ") + `)
+		GROUP BY targetTxID, targetActionIndex, reaction
+		ORDER BY targetTxID, targetActionIndex, reaction`
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching reaction totals: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []ReactionTotal
+	for rows.Next() {
+		var (
+			total                ReactionTotal
+			billions, remainders uint64
+		)
+		if err := rows.Scan(&total.TargetTxID, &total.TargetActionIndex, &total.Reaction, &total.Count, &billions, &remainders); err != nil {
+			log.Printf("Error scanning reaction total row: %v", err)
+			return nil, err
+		}
+		total.Tips = joinAmounts(billions, remainders)
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetAuthorStats returns the stats of the feeds posted by [address] on
+// [subnetID] and [chainID]. Tips are capped at math.MaxUint64.
+func (db *DB) GetAuthorStats(subnetID, chainID, address string) (*AuthorStats, error) {
+	var stats AuthorStats
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM feeds WHERE subnetID = $1 AND chainID = $2 AND address = $3 AND NOT hidden AND NOT deleted`,
+		subnetID, chainID, address).Scan(&stats.Posts)
+	if err != nil {
+		log.Printf("Error counting feeds of %s: %v", address, err)
+		return nil, err
+	}
+	query := `SELECT COUNT(*), ` + db.sumAmounts("r.value") + ` FROM reactions r
+		JOIN feeds f ON f.txid = r.targetTxID AND f.actionIndex = r.targetActionIndex
+		WHERE f.subnetID = $1 AND f.chainID = $2 AND f.address = $3 AND NOT f.hidden`
+	var billions, remainders uint64
+	if err := db.conn.QueryRow(query, subnetID, chainID, address).Scan(&stats.Reactions, &billions, &remainders); err != nil {
+		log.Printf("Error summing reactions to %s: %v", address, err)
+		return nil, err
+	}
+	stats.Tips = joinAmounts(billions, remainders)
+	return &stats, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"log"
+)
+
+// Revision actions.
+const (
+	RevisionPost   = "post"
+	RevisionEdit   = "edit"
+	RevisionDelete = "delete"
+)
+
+// RevisionObject is a version of the feed posted by action [ActionIndex] of
+// [TxID]. Revision 0 is the original post, recorded when the feed is first
+// revised; each edit or deletion by its author adds the next revision, made
+// by action [RevisionActionIndex] of [RevisionTxID].
+type RevisionObject struct {
+	ChainID             string `json:"chainID"`
+	TxID                string `json:"txID"`
+	ActionIndex         int    `json:"actionIndex"`
+	Revision            int    `json:"revision"`
+	Action              string `json:"action"`
+	RevisionTxID        string `json:"revisionTxID"`
+	RevisionActionIndex int    `json:"revisionActionIndex"`
+	Message             string `json:"message"`
+	URL                 string `json:"url"`
+	Encoding            string `json:"encoding"`
+	Timestamp           int64  `json:"timestamp"`
+	Height              uint64 `json:"height"`
+}
+
+// saveRevision records [rev] and makes it the current version of its feed.
+// Revisions that were already recorded are left untouched.
+func saveRevision(tx *sql.Tx, rev *RevisionObject) error {
+	query := `INSERT INTO feed_revisions (chainID, txid, actionIndex, revision, action, revisionTxID, revisionActionIndex, message, url, encoding, timestamp, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (txid, actionIndex, revision) DO NOTHING`
+	res, err := tx.Exec(query, rev.ChainID, rev.TxID, rev.ActionIndex, rev.Revision, rev.Action, rev.RevisionTxID, rev.RevisionActionIndex, rev.Message, rev.URL, rev.Encoding, rev.Timestamp, rev.Height)
+	if err != nil {
+		log.Printf("Error saving revision: %v", err)
+		return err
+	}
+	saved, err := res.RowsAffected()
+	if err != nil || saved == 0 || rev.Revision == 0 {
+		return err
+	}
+	return applyRevision(tx, rev)
+}
+
+// applyRevision sets the content of the feed revised by [rev] to it.
+func applyRevision(ex execer, rev *RevisionObject) error {
+	query := `UPDATE feeds SET message = $1, url = $2, encoding = $3, revision = $4, deleted = $5 WHERE txid = $6 AND actionIndex = $7`
+	_, err := ex.Exec(query, rev.Message, rev.URL, rev.Encoding, rev.Revision, rev.Action == RevisionDelete, rev.TxID, rev.ActionIndex)
+	if err != nil {
+		log.Printf("Error applying revision %d of %s: %v", rev.Revision, rev.TxID, err)
+	}
+	return err
+}
+
+// rollbackRevisions removes the revisions made on [chainID] at or above
+// [height] outside of final blocks and restores the latest remaining version
+// of the feeds they revised.
+func rollbackRevisions(tx *sql.Tx, chainID string, height uint64) error {
+	const filter = `FROM feed_revisions WHERE chainID = $1 AND height >= $2 AND height NOT IN (SELECT height FROM blocks WHERE chainID = $1 AND final)`
+	rows, err := tx.Query(`SELECT DISTINCT txid, actionIndex `+filter, chainID, height)
+	if err != nil {
+		log.Printf("Error fetching revisions to roll back: %v", err)
+		return err
+	}
+	var revised []feedKey
+	for rows.Next() {
+		var key feedKey
+		if err := rows.Scan(&key.txID, &key.actionIndex); err != nil {
+			rows.Close()
+			log.Printf("Error scanning revision row: %v", err)
+			return err
+		}
+		revised = append(revised, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE `+filter, chainID, height); err != nil {
+		log.Printf("Error rolling back revisions: %v", err)
+		return err
+	}
+
+	for _, key := range revised {
+		rev := RevisionObject{TxID: key.txID, ActionIndex: key.actionIndex}
+		err := tx.QueryRow(`SELECT revision, action, message, url, encoding FROM feed_revisions
+			WHERE txid = $1 AND actionIndex = $2 ORDER BY revision DESC LIMIT 1`, key.txID, key.actionIndex).
+			Scan(&rev.Revision, &rev.Action, &rev.Message, &rev.URL, &rev.Encoding)
+		if err == sql.ErrNoRows {
+			// The feed itself was rolled back.
+			continue
+		}
+		if err != nil {
+			log.Printf("Error fetching revision of %s: %v", key.txID, err)
+			return err
+		}
+		if err := applyRevision(tx, &rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"testing"
+)
+
+// getRevisions returns the recorded versions of the feed posted by action
+// [actionIndex] of [txID], in order.
+func getRevisions(t *testing.T, store Store, txID string, actionIndex int) []RevisionObject {
+	t.Helper()
+
+	switch db := store.(type) {
+	case *MemoryDB:
+		db.l.RLock()
+		defer db.l.RUnlock()
+		return db.revisions[feedKey{txID, actionIndex}]
+	case *DB:
+		rows, err := db.conn.Query(`SELECT chainID, revision, action, message FROM feed_revisions
+			WHERE txid = $1 AND actionIndex = $2 ORDER BY revision`, txID, actionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var revs []RevisionObject
+		for rows.Next() {
+			rev := RevisionObject{TxID: txID, ActionIndex: actionIndex}
+			if err := rows.Scan(&rev.ChainID, &rev.Revision, &rev.Action, &rev.Message); err != nil {
+				t.Fatal(err)
+			}
+			revs = append(revs, rev)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return revs
+	default:
+		t.Fatalf("unknown store %T", store)
+		return nil
+	}
+}
+
+// indexEdit indexes [feed] at height 1 of its chain and an edit of it to
+// [message] at height 2, recording the original post as revision 0.
+func indexEdit(t *testing.T, db Store, feed FeedObject, message string) {
+	t.Helper()
+
+	if err := db.IndexBlock(&BlockObject{ChainID: feed.ChainID, Height: 1, BlockID: uniqueID("block")}, []FeedObject{feed}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	revisions := []RevisionObject{
+		{ChainID: feed.ChainID, TxID: feed.TxID, Action: RevisionPost, RevisionTxID: feed.TxID, Message: feed.Message, Height: feed.Height},
+		{ChainID: feed.ChainID, TxID: feed.TxID, Revision: 1, Action: RevisionEdit, RevisionTxID: uniqueID("edit"), Message: message, Height: 2},
+	}
+	if err := db.IndexBlock(&BlockObject{ChainID: feed.ChainID, Height: 2, BlockID: uniqueID("block")}, nil, nil, revisions, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkMessage(t *testing.T, db Store, txID, message string, revision int) {
+	t.Helper()
+
+	feed, err := db.GetFeed(txID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Message != message || feed.Revision != revision {
+		t.Fatalf("feed has message %q at revision %d, want %q at %d", feed.Message, feed.Revision, message, revision)
+	}
+}
+
+func TestRevisionKeepsOriginal(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			feed := FeedObject{TxID: uniqueID("feed"), SubnetID: "subnet", ChainID: uniqueID("chain"), Address: "author", Message: "original", Height: 1, Status: StatusFinal}
+			indexEdit(t, db, feed, "edited")
+			checkMessage(t, db, feed.TxID, "edited", 1)
+
+			revs := getRevisions(t, db, feed.TxID, 0)
+			if len(revs) != 2 {
+				t.Fatalf("got %d revisions, want 2", len(revs))
+			}
+			if revs[0].Revision != 0 || revs[0].Action != RevisionPost || revs[0].Message != "original" {
+				t.Fatalf("revision 0 = %+v, want the original post", revs[0])
+			}
+			if revs[1].Revision != 1 || revs[1].Action != RevisionEdit || revs[1].Message != "edited" {
+				t.Fatalf("revision 1 = %+v, want the edit", revs[1])
+			}
+		})
+	}
+}
+
+func TestRollbackRevisionsPerChain(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			rolledBack := FeedObject{TxID: uniqueID("feed"), SubnetID: "subnet", ChainID: uniqueID("chain"), Address: "author", Message: "original", Height: 1, Status: StatusFinal}
+			kept := FeedObject{TxID: uniqueID("feed"), SubnetID: "subnet", ChainID: uniqueID("chain"), Address: "author", Message: "original", Height: 1, Status: StatusFinal}
+			indexEdit(t, db, rolledBack, "edited")
+			indexEdit(t, db, kept, "edited")
+
+			if err := db.RollbackFrom(rolledBack.ChainID, 2); err != nil {
+				t.Fatal(err)
+			}
+			checkMessage(t, db, rolledBack.TxID, "original", 0)
+			if revs := getRevisions(t, db, rolledBack.TxID, 0); len(revs) != 1 || revs[0].Revision != 0 {
+				t.Fatalf("revisions after rollback = %+v, want only the original post", revs)
+			}
+			// The edit made at the same height on the other chain is kept.
+			checkMessage(t, db, kept.TxID, "edited", 1)
+			if revs := getRevisions(t, db, kept.TxID, 0); len(revs) != 2 {
+				t.Fatalf("revisions of the other chain = %+v, want 2", revs)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SearchQuery selects the feeds returned by SearchFeeds. Empty fields match
+// every feed on the subnet and chain.
+type SearchQuery struct {
+	SubnetID string
+	ChainID  string
+	// Text must appear in the message. On Postgres it is matched with
+	// full-text search, elsewhere every word must appear as a substring.
+	Text    string
+	Address string
+	From    int64 // inclusive, zero for no lower bound
+	To      int64 // exclusive, zero for no upper bound
+}
+
+// escapeLike escapes the LIKE wildcards in [s] using backslash.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchFeeds returns up to [limit] feeds matching [query], paginated like
+// GetLastFeeds.
+func (db *DB) SearchFeeds(query *SearchQuery, cursor *PageCursor, limit int) ([]FeedObject, error) {
+	filter := []string{`subnetID = $1`, `chainID = $2`}
+	args := []any{query.SubnetID, query.ChainID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(query.Address) > 0 {
+		filter = append(filter, `address = `+arg(query.Address))
+	}
+	if query.From > 0 {
+		filter = append(filter, `timestamp >= `+arg(query.From))
+	}
+	if query.To > 0 {
+		filter = append(filter, `timestamp < `+arg(query.To))
+	}
+	if terms := strings.Fields(query.Text); len(terms) > 0 {
+		if db.dialect == DialectPostgres {
+			filter = append(filter, `searchVector @@ plainto_tsquery('simple', `+arg(query.Text)+`)`)
+		} else {
+			// LIKE is case-insensitive for ASCII in SQLite.
+			for _, term := range terms {
+				filter = append(filter, `message LIKE `+arg("%"+escapeLike(term)+"%")+` ESCAPE '\'`)
+			}
+		}
+	}
+
+	feeds, err := db.queryFeedPage(strings.Join(filter, " AND "), args, cursor, limit)
+	if err != nil {
+		log.Printf("Error searching feeds: %v", err)
+	}
+	return feeds, err
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"database/sql"
+	"log"
+
+	_ "modernc.org/sqlite"
+)
+
+// OpenSQLite opens the embedded SQLite database at [path], creating the file
+// if needed. ":memory:" keeps the database in memory.
+func OpenSQLite(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		log.Printf("Error opening SQLite database: %v", err)
+		return nil, err
+	}
+	// SQLite only allows a single writer, and every connection to ":memory:"
+	// would otherwise get its own empty database.
+	conn.SetMaxOpenConns(1)
+	return conn, nil
+}
+
+// NewSQLiteDB creates a Store backed by the embedded SQLite database at
+// [path], applying any pending migrations.
+func NewSQLiteDB(path string) (*DB, error) {
+	conn, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := newDB(conn, DialectSQLite)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+// Store persists indexed feeds together with the ingestion and fee state the
+// manager needs to resume after a restart.
+type Store interface {
+	SaveFeed(*FeedObject) error
+	GetFeed(txID string, actionIndex int) (*FeedObject, error)
+	GetFeedsByTxID(txID string) ([]FeedObject, error)
+	GetAllFeeds() ([]FeedObject, error)
+	GetFeedsByUser(subnetID, chainID, address string, cursor *PageCursor, limit int) ([]FeedObject, error)
+	GetLastFeeds(subnetID, chainID string, cursor *PageCursor, limit int) ([]FeedObject, error)
+	SearchFeeds(query *SearchQuery, cursor *PageCursor, limit int) ([]FeedObject, error)
+	GetThread(txID string, actionIndex int, limit int) ([]FeedObject, error)
+	CountReplies(txID string, actionIndex int) (int, error)
+	GetReactionTotals(txIDs []string) ([]ReactionTotal, error)
+	GetAuthorStats(subnetID, chainID, address string) (*AuthorStats, error)
+
+	GetBlockCursor(chainID string) (uint64, bool, error)
+	SaveBlockCursor(chainID string, height uint64) error
+	GetBlock(chainID string, height uint64) (*BlockObject, bool, error)
+	SaveBlock(*BlockObject) error
+	IndexBlock(blk *BlockObject, feeds []FeedObject, reactions []ReactionObject, revisions []RevisionObject, epoch *FeeEpoch) error
+	SkipBlocks(*BlockGap) error
+	GetBlockGaps(chainID string) ([]BlockGap, error)
+	RollbackFrom(chainID string, height uint64) error
+	FinalizeBlocks(chainID string, height uint64) error
+
+	SaveFeeEpoch(*FeeEpoch) error
+	RotateFeeEpoch(closed, opened *FeeEpoch) error
+	GetCurrentFeeEpoch() (*FeeEpoch, bool, error)
+	GetFeeHistory(limit int) ([]FeeEpoch, error)
+
+	SetFeedsHidden(txID string, actionIndex *int, hidden bool, entry *ModerationEntry) error
+	SetAuthorBanned(address string, banned bool, entry *ModerationEntry) error
+	GetBannedAuthors() ([]string, error)
+	GetModerationLog(limit int) ([]ModerationEntry, error)
+
+	Close()
+}
@@ -0,0 +1,34 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import "log"
+
+// GetThread returns up to [limit] feeds of the thread started by the feed
+// posted by action [actionIndex] of [txID], including that feed, oldest
+// first. Hidden feeds are skipped.
+func (db *DB) GetThread(txID string, actionIndex int, limit int) ([]FeedObject, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds
+		WHERE ((txid = $1 AND actionIndex = $2) OR (rootTxID = $1 AND rootActionIndex = $2)) AND NOT hidden
+		ORDER BY timestamp, txid, actionIndex LIMIT $3`
+	rows, err := db.conn.Query(query, txID, actionIndex, limit)
+	if err != nil {
+		log.Printf("Error fetching thread of TxID %s, action %d: %v", txID, actionIndex, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFeeds(rows)
+}
+
+// CountReplies returns the number of visible direct replies to the feed
+// posted by action [actionIndex] of [txID].
+func (db *DB) CountReplies(txID string, actionIndex int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM feeds WHERE parentTxID = $1 AND parentActionIndex = $2 AND NOT hidden`, txID, actionIndex).Scan(&count)
+	if err != nil {
+		log.Printf("Error counting replies of TxID %s, action %d: %v", txID, actionIndex, err)
+	}
+	return count, err
+}
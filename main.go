@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -18,8 +19,8 @@ import (
 	"github.com/ava-labs/hypersdk/server"
 	"github.com/ava-labs/hypersdk/utils"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	"github.com/nuklai/nuklai-feed/config"
+	"github.com/nuklai/nuklai-feed/database"
 	"github.com/nuklai/nuklai-feed/manager"
 	frpc "github.com/nuklai/nuklai-feed/rpc"
 	"go.uber.org/zap"
@@ -45,6 +46,123 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
+// openStore opens the storage backend selected in [config].
+func openStore(log logging.Logger, config *config.Config) (database.Store, error) {
+	switch config.DatabaseBackend {
+	case database.DialectPostgres:
+		conn, err := openPostgres(log, config)
+		if err != nil {
+			return nil, err
+		}
+		return database.NewPostgresDB(conn)
+	case database.DialectSQLite:
+		return database.NewSQLiteDB(config.SQLitePath)
+	case "memory":
+		return database.NewMemoryDB(), nil
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", config.DatabaseBackend)
+	}
+}
+
+// runMigrate implements the migrate subcommand:
+//
+//	migrate [status]      lists migrations and whether they are applied
+//	migrate up            applies all pending migrations
+//	migrate down [steps]  reverts the last [steps] migrations (default 1)
+func runMigrate(log logging.Logger, config *config.Config, args []string) error {
+	var conn *sql.DB
+	var err error
+	switch config.DatabaseBackend {
+	case database.DialectPostgres:
+		conn, err = openPostgres(log, config)
+	case database.DialectSQLite:
+		conn, err = database.OpenSQLite(config.SQLitePath)
+	default:
+		return fmt.Errorf("database backend %q does not use migrations", config.DatabaseBackend)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := database.NewMigrator(conn, config.DatabaseBackend)
+	if err != nil {
+		return err
+	}
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "status":
+		if err := migrator.CheckVersion(); err != nil {
+			return err
+		}
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != 0 {
+				applied = "applied " + time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			utils.Outf("%04d_%s: %s\n", status.Version, status.Name, applied)
+		}
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations applied", zap.Int("count", applied), zap.Int("version", migrator.Latest()))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations reverted", zap.Int("steps", steps), zap.Int("version", version))
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected status, up or down", command)
+	}
+	return nil
+}
+
+// openPostgres connects to PostgreSQL, retrying while the server starts up.
+func openPostgres(log logging.Logger, config *config.Config) (*sql.DB, error) {
+	var (
+		db  *sql.DB
+		err error
+	)
+	for i := 0; i < 10; i++ {
+		connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			config.PostgresHost, config.PostgresPort, config.PostgresUser, config.PostgresPassword, config.PostgresDBName, config.PostgresSSLMode)
+		db, err = sql.Open("postgres", connStr)
+		if err != nil {
+			log.Warn("Error opening database", zap.Error(err))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		err = db.Ping()
+		if err == nil {
+			return db, nil
+		}
+		log.Warn("Database not ready, retrying...", zap.Error(err))
+		time.Sleep(5 * time.Second)
+	}
+	return nil, err
+}
+
 func main() {
 	err := godotenv.Overload() // Overload the environment variables with those from the .env file
 	if err != nil {
@@ -70,6 +188,20 @@ func main() {
 		fatal(log, "cannot load config from environment variables", zap.Error(err))
 	}
 	log.Info("Config loaded from environment variables")
+	adminToken := config.AdminTokenEnabled && len(config.AdminToken) > 0
+	if len(config.AdminToken) > 0 && !config.AdminTokenEnabled {
+		log.Warn("ADMIN_TOKEN is ignored unless ADMIN_TOKEN_ENABLED is set")
+	}
+	if len(config.AdminPublicKeys) == 0 && !adminToken {
+		log.Warn("No admin public keys configured, admin methods are disabled")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(log, config, os.Args[2:]); err != nil {
+			fatal(log, "migration failed", zap.Error(err))
+		}
+		return
+	}
 
 	// Load recipient
 	if _, err := config.RecipientAddress(); err != nil {
@@ -98,29 +230,12 @@ func main() {
 	mux.HandleFunc("/health", HealthHandler)
 	log.Info("Health handler added")
 
-	// Retry mechanism for PostgreSQL connection
-	var db *sql.DB
-	for i := 0; i < 10; i++ {
-		connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			config.PostgresHost, config.PostgresPort, config.PostgresUser, config.PostgresPassword, config.PostgresDBName, config.PostgresSSLMode)
-		db, err = sql.Open("postgres", connStr)
-		if err != nil {
-			log.Warn("Error opening database", zap.Error(err))
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		err = db.Ping()
-		if err == nil {
-			break
-		}
-		log.Warn("Database not ready, retrying...", zap.Error(err))
-		time.Sleep(5 * time.Second)
-	}
-
+	db, err := openStore(log, config)
 	if err != nil {
-		fatal(log, "could not connect to the database", zap.Error(err))
+		fatal(log, "could not open the database", zap.Error(err))
 	}
-	log.Info("Database connection established")
+	defer db.Close()
+	log.Info("Database connection established", zap.String("backend", config.DatabaseBackend))
 
 	// Start manager with context handling
 	manager, err := manager.New(log, config, db)
@@ -146,6 +261,25 @@ func main() {
 	mux.Handle("/", handler)
 	log.Info("Feed handler added")
 
+	// Add websocket handler for live posts
+	mux.Handle(frpc.WebSocketEndpoint, frpc.NewWebSocketServer(manager))
+	log.Info("WebSocket handler added", zap.String("path", frpc.WebSocketEndpoint))
+
+	// Add server-sent events handler for live posts
+	mux.Handle(frpc.EventsEndpoint, frpc.NewEventStreamServer(manager))
+	log.Info("Event stream handler added", zap.String("path", frpc.EventsEndpoint))
+
+	// Add RSS, Atom and JSON Feed handlers
+	syndicationServer := frpc.NewSyndicationServer(manager)
+	for _, path := range []string{frpc.RSSEndpoint, frpc.AtomEndpoint, frpc.JSONFeedEndpoint} {
+		mux.Handle(path, syndicationServer)
+	}
+	log.Info("Syndication handlers added")
+
+	// Add REST handler
+	mux.Handle(frpc.RESTEndpoint, frpc.NewRESTServer(manager))
+	log.Info("REST handler added", zap.String("path", frpc.RESTEndpoint))
+
 	// Start server
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
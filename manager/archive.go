@@ -0,0 +1,79 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/rpc"
+)
+
+const (
+	archiveTimeout      = 30 * time.Second
+	maxArchivedBlockLen = 16 * units.MiB
+)
+
+var _ BlockFetcher = (*ArchiveFetcher)(nil)
+
+// ArchiveFetcher is a BlockFetcher that reads blocks from an HTTP archive of
+// the chain. GET <url>/<height> must return the block accepted at that
+// height with its results, packed like the blocks streamed by Nuklai nodes
+// (see rpc.PackBlockMessage), or 404 if the archive does not have it.
+type ArchiveFetcher struct {
+	url    string
+	client *http.Client
+}
+
+// NewArchiveFetcher returns an ArchiveFetcher reading from [url].
+func NewArchiveFetcher(url string) *ArchiveFetcher {
+	return &ArchiveFetcher{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: archiveTimeout},
+	}
+}
+
+func (f *ArchiveFetcher) GetBlockByHeight(ctx context.Context, height uint64, parser chain.Parser) (*chain.StatefulBlock, []*chain.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%d", f.url, height), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch block %d: %w", height, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil, fmt.Errorf("%w: block %d is not archived", ErrBlockUnavailable, height)
+	default:
+		return nil, nil, fmt.Errorf("failed to fetch block %d: archive returned %s", height, resp.Status)
+	}
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, maxArchivedBlockLen+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read block %d: %w", height, err)
+	}
+	if len(msg) > maxArchivedBlockLen {
+		return nil, nil, fmt.Errorf("archived block %d is larger than %d bytes", height, maxArchivedBlockLen)
+	}
+	blk, results, _, err := rpc.UnpackBlockMessage(msg, parser)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse block %d: %w", height, err)
+	}
+	if blk.Hght != height {
+		return nil, nil, fmt.Errorf("archive returned block %d for height %d", blk.Hght, height)
+	}
+	if len(results) != len(blk.Txs) {
+		return nil, nil, fmt.Errorf("archived block %d has %d results for %d transactions", height, len(results), len(blk.Txs))
+	}
+	return blk, results, nil
+}
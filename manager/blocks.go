@@ -0,0 +1,42 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ava-labs/hypersdk/chain"
+)
+
+// ErrBlockUnavailable is returned by a BlockFetcher that cannot serve a
+// block, for example because the node pruned it.
+var ErrBlockUnavailable = errors.New("block unavailable")
+
+// BlockFetcher retrieves accepted blocks by height. It is used to fill in
+// blocks that were produced while the websocket stream was unavailable.
+//
+// Nuklai RPC nodes do not serve past blocks, so missed blocks are fetched
+// from the archive set in BLOCK_ARCHIVE_URL, see ArchiveFetcher. Blocks that
+// cannot be fetched are recorded as gaps and retried.
+type BlockFetcher interface {
+	GetBlockByHeight(context.Context, uint64, chain.Parser) (*chain.StatefulBlock, []*chain.Result, error)
+}
+
+// SetBlockFetcher fills in missed blocks with [fetcher], such as an archive
+// of the chain, from then on.
+func (m *Manager) SetBlockFetcher(fetcher BlockFetcher) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.fetcher = fetcher
+}
+
+// blockFetcher returns the configured BlockFetcher, or nil.
+func (m *Manager) blockFetcher() BlockFetcher {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.fetcher
+}
@@ -0,0 +1,328 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/consts"
+	"github.com/ava-labs/hypersdk/fees"
+	"github.com/nuklai/nuklai-feed/database"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+)
+
+// fakeFetcher serves [blocks] and reports any other height as unavailable.
+type fakeFetcher struct {
+	blocks  map[uint64]*chain.StatefulBlock
+	fetched []uint64
+}
+
+func (f *fakeFetcher) GetBlockByHeight(_ context.Context, height uint64, _ chain.Parser) (*chain.StatefulBlock, []*chain.Result, error) {
+	f.fetched = append(f.fetched, height)
+	blk, ok := f.blocks[height]
+	if !ok {
+		return nil, nil, ErrBlockUnavailable
+	}
+	return blk, []*chain.Result{}, nil
+}
+
+// indexUpTo ingests [blocks] up to [height] as if they came from the stream.
+func indexUpTo(t *testing.T, m *Manager, blocks []*chain.StatefulBlock, height uint64) {
+	t.Helper()
+
+	for _, blk := range blocks[1 : height+1] {
+		if err := m.handleBlock(context.Background(), nil, blk, []*chain.Result{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkCursor(t *testing.T, m *Manager, want uint64) {
+	t.Helper()
+
+	cursor, ok, err := m.db.GetBlockCursor(m.chainID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || cursor != want {
+		t.Fatalf("cursor = %d (%t), want %d", cursor, ok, want)
+	}
+}
+
+func TestBackfillFromFetcher(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	blocks := testBlocks(t, 6)
+	indexUpTo(t, m, blocks, 2)
+
+	fetcher := &fakeFetcher{blocks: map[uint64]*chain.StatefulBlock{3: blocks[3], 4: blocks[4], 5: blocks[5]}}
+	m.SetBlockFetcher(fetcher)
+	if err := m.handleBlock(context.Background(), nil, blocks[6], []*chain.Result{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fetcher.fetched) != 3 {
+		t.Fatalf("fetched %v, want 3 to 5", fetcher.fetched)
+	}
+	checkCursor(t, m, 6)
+	for height := uint64(1); height <= 6; height++ {
+		stored, ok, err := db.GetBlock(m.chainID.String(), height)
+		if err != nil || !ok {
+			t.Fatalf("block %d not indexed: %v", height, err)
+		}
+		if want := blockID(t, blocks[height]).String(); stored.BlockID != want {
+			t.Fatalf("block %d = %s, want %s", height, stored.BlockID, want)
+		}
+	}
+}
+
+func checkGaps(t *testing.T, m *Manager, want ...database.BlockGap) {
+	t.Helper()
+
+	gaps, err := m.db.GetBlockGaps(m.chainID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(gaps, want) {
+		t.Fatalf("gaps = %+v, want %+v", gaps, want)
+	}
+}
+
+func TestBackfillWithoutFetcherRecordsGap(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	blocks := testBlocks(t, 6)
+	indexUpTo(t, m, blocks, 2)
+
+	if err := m.handleBlock(context.Background(), nil, blocks[6], []*chain.Result{}); err != nil {
+		t.Fatalf("live block not ingested after gap: %v", err)
+	}
+	checkCursor(t, m, 6)
+	for height := uint64(3); height <= 5; height++ {
+		if _, ok, _ := db.GetBlock(m.chainID.String(), height); ok {
+			t.Fatalf("block %d indexed without a fetcher", height)
+		}
+	}
+	checkGaps(t, m, database.BlockGap{ChainID: m.chainID.String(), From: 3, To: 5})
+}
+
+func TestBackfillStopsAtUnavailableBlock(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	blocks := testBlocks(t, 6)
+	indexUpTo(t, m, blocks, 2)
+
+	fetcher := &fakeFetcher{blocks: map[uint64]*chain.StatefulBlock{3: blocks[3], 5: blocks[5]}}
+	m.SetBlockFetcher(fetcher)
+	if err := m.handleBlock(context.Background(), nil, blocks[6], []*chain.Result{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fetcher.fetched) != 2 {
+		t.Fatalf("fetched %v, want 3 and 4", fetcher.fetched)
+	}
+	checkCursor(t, m, 6)
+	if _, ok, _ := db.GetBlock(m.chainID.String(), 3); !ok {
+		t.Fatal("block 3 not indexed")
+	}
+	if _, ok, _ := db.GetBlock(m.chainID.String(), 5); ok {
+		t.Fatal("block 5 indexed past the gap")
+	}
+	checkGaps(t, m, database.BlockGap{ChainID: m.chainID.String(), From: 4, To: 5})
+}
+
+func TestFillGaps(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	blocks := testBlocks(t, 9)
+	indexUpTo(t, m, blocks, 2)
+	if err := m.handleBlock(context.Background(), nil, blocks[6], []*chain.Result{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.handleBlock(context.Background(), nil, blocks[9], []*chain.Result{}); err != nil {
+		t.Fatal(err)
+	}
+	chainID := m.chainID.String()
+	checkGaps(t, m, database.BlockGap{ChainID: chainID, From: 3, To: 5}, database.BlockGap{ChainID: chainID, From: 7, To: 8})
+
+	// Without a fetcher the gaps are kept.
+	if err := m.fillGaps(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	checkGaps(t, m, database.BlockGap{ChainID: chainID, From: 3, To: 5}, database.BlockGap{ChainID: chainID, From: 7, To: 8})
+
+	// The archive only has blocks 3, 4, 5 and 7 so far.
+	fetcher := &fakeFetcher{blocks: map[uint64]*chain.StatefulBlock{3: blocks[3], 4: blocks[4], 5: blocks[5], 7: blocks[7]}}
+	m.SetBlockFetcher(fetcher)
+	if err := m.fillGaps(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	checkCursor(t, m, 9)
+	checkGaps(t, m, database.BlockGap{ChainID: chainID, From: 8, To: 8})
+
+	fetcher.blocks[8] = blocks[8]
+	if err := m.fillGaps(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	checkCursor(t, m, 9)
+	checkGaps(t, m)
+	for height := uint64(1); height <= 9; height++ {
+		if _, ok, err := db.GetBlock(chainID, height); err != nil || !ok {
+			t.Fatalf("block %d not indexed: %v", height, err)
+		}
+	}
+}
+
+// testParser parses the transactions of the Nuklai VM.
+type testParser struct{}
+
+func (testParser) Rules(int64) chain.Rules { return nil }
+
+func (testParser) Registry() (chain.ActionRegistry, chain.AuthRegistry) {
+	return nconsts.ActionRegistry, nconsts.AuthRegistry
+}
+
+// packBlock packs [blk] and [results] like rpc.PackBlockMessage.
+func packBlock(t *testing.T, blk *chain.StatefulBlock, results []*chain.Result) []byte {
+	t.Helper()
+
+	blkBytes, err := blk.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultsBytes, err := chain.MarshalResults(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := codec.NewWriter(0, consts.MaxInt)
+	p.PackBytes(blkBytes)
+	p.PackBytes(resultsBytes)
+	p.PackFixedBytes(fees.Dimensions{}.Bytes())
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return p.Bytes()
+}
+
+func TestArchiveFetcher(t *testing.T) {
+	m := newTestManager(t, database.NewMemoryDB())
+	blk := &chain.StatefulBlock{Tmstmp: 5000, Hght: 5, Txs: []*chain.Transaction{testTransfer(t, m, 100, "archived")}}
+	archived := packBlock(t, blk, []*chain.Result{{Success: true}})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocks/5":
+			_, _ = w.Write(archived)
+		case "/blocks/6":
+			_, _ = w.Write([]byte("not a block"))
+		case "/blocks/7":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	fetcher := NewArchiveFetcher(srv.URL + "/blocks/")
+
+	got, results, err := fetcher.GetBlockByHeight(context.Background(), 5, testParser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockID(t, got) != blockID(t, blk) || len(results) != 1 || !results[0].Success {
+		t.Fatalf("fetched block %d with results %+v, want block 5 with one successful result", got.Hght, results)
+	}
+	if _, _, err := fetcher.GetBlockByHeight(context.Background(), 4, testParser{}); !errors.Is(err, ErrBlockUnavailable) {
+		t.Fatalf("missing block: err = %v, want %v", err, ErrBlockUnavailable)
+	}
+	for _, height := range []uint64{6, 7} {
+		if _, _, err := fetcher.GetBlockByHeight(context.Background(), height, testParser{}); err == nil || errors.Is(err, ErrBlockUnavailable) {
+			t.Fatalf("block %d: err = %v, want a fetch error", height, err)
+		}
+	}
+}
+
+func TestReorgWithoutFetcher(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	blocks := testBlocks(t, 3)
+	indexUpTo(t, m, blocks, 3)
+
+	// A competing block 3 replaces the indexed one, and the block built on it
+	// arrives before it could be fetched.
+	fork := &chain.StatefulBlock{Prnt: blockID(t, blocks[2]), Tmstmp: 3500, Hght: 3}
+	next := &chain.StatefulBlock{Prnt: blockID(t, fork), Tmstmp: 4000, Hght: 4}
+	if err := m.handleBlock(context.Background(), nil, next, []*chain.Result{}); err != nil {
+		t.Fatalf("block after reorg not ingested: %v", err)
+	}
+	checkCursor(t, m, 4)
+	if _, ok, _ := db.GetBlock(m.chainID.String(), 3); ok {
+		t.Fatal("orphaned block 3 was not rolled back")
+	}
+}
+
+func TestForcedChainSwitch(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	m.config.FinalityDepth = 2
+	oldChain := m.chainID.String()
+
+	// Blocks 1 to 6 of the old chain, with a post in block 2, which becomes
+	// final, and one in block 5, which is still pending.
+	ingest := func(blk *chain.StatefulBlock) {
+		t.Helper()
+		results := make([]*chain.Result, len(blk.Txs))
+		for i := range results {
+			results[i] = &chain.Result{Success: true}
+		}
+		if err := m.handleBlock(context.Background(), nil, blk, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		parent ids.ID
+		posts  []string
+	)
+	for height := uint64(1); height <= 6; height++ {
+		blk := &chain.StatefulBlock{Prnt: parent, Tmstmp: int64(height) * 1000, Hght: height}
+		if height == 2 || height == 5 {
+			tx := testTransfer(t, m, 100, "old chain")
+			blk.Txs = []*chain.Transaction{tx}
+			posts = append(posts, tx.ID().String())
+		}
+		ingest(blk)
+		parent = blockID(t, blk)
+	}
+
+	// The new chain has other blocks at the same heights.
+	m.useEndpoint(&endpoint{chainID: ids.GenerateTestID()})
+	parent = ids.Empty
+	for height := uint64(1); height <= 6; height++ {
+		blk := &chain.StatefulBlock{Prnt: parent, Tmstmp: int64(height)*1000 + 1, Hght: height}
+		ingest(blk)
+		parent = blockID(t, blk)
+	}
+
+	checkCursor(t, m, 6)
+	if cursor, ok, err := db.GetBlockCursor(oldChain); err != nil || !ok || cursor != 6 {
+		t.Fatalf("old chain cursor = %d (%t, %v), want 6", cursor, ok, err)
+	}
+	for _, txID := range posts {
+		if _, err := db.GetFeed(txID, 0); err != nil {
+			t.Fatalf("post %s of the old chain was rolled back: %v", txID, err)
+		}
+	}
+	for height := uint64(1); height <= 6; height++ {
+		if _, ok, _ := db.GetBlock(oldChain, height); !ok {
+			t.Fatalf("block %d of the old chain was rolled back", height)
+		}
+	}
+}
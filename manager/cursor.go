@@ -0,0 +1,108 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	cursorOlder = "o"
+	cursorNewer = "n"
+)
+
+// FeedPage is a page of posts ordered newest first. Next pages towards older
+// posts and is empty once the oldest post is reached. Prev pages towards
+// newer posts and can be polled for posts published after the page.
+type FeedPage struct {
+	Feed []*FeedObject
+	Next string
+	Prev string
+}
+
+// encodeCursor returns an opaque cursor pointing at the stored [feed] that
+// pages in the given direction.
+func encodeCursor(direction string, feed *database.FeedObject) string {
+	raw := fmt.Sprintf("%s:%d:%s:%d", direction, feed.Timestamp, feed.TxID, feed.ActionIndex)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Cursor returns a cursor that pages from [feed] towards newer posts. It is
+// used to resume a stream of posts after [feed].
+func (feed *FeedObject) Cursor() string {
+	return encodeCursor(cursorNewer, &database.FeedObject{
+		Timestamp:   feed.Timestamp,
+		TxID:        feed.TxID.String(),
+		ActionIndex: feed.ActionIndex,
+	})
+}
+
+// decodeCursor parses a cursor created by encodeCursor. An empty cursor
+// decodes to nil, which starts at the newest post.
+func decodeCursor(cursor string) (*database.PageCursor, error) {
+	if len(cursor) == 0 {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || (parts[0] != cursorOlder && parts[0] != cursorNewer) {
+		return nil, ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	actionIndex, err := strconv.Atoi(parts[3])
+	if err != nil || actionIndex < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &database.PageCursor{
+		Timestamp:   timestamp,
+		TxID:        parts[2],
+		ActionIndex: actionIndex,
+		Newer:       parts[0] == cursorNewer,
+	}, nil
+}
+
+// newFeedPage builds the page for [feed], which was fetched after [cursor]
+// with one extra row to detect whether more posts exist past [limit]. [raw]
+// is the encoded form of [cursor]. The cursors are taken from the stored
+// rows, so skipping malformed rows does not affect pagination.
+func (m *Manager) newFeedPage(feed []database.FeedObject, raw string, cursor *database.PageCursor, limit int) *FeedPage {
+	more := len(feed) > limit
+	if more {
+		if cursor != nil && cursor.Newer {
+			feed = feed[1:]
+		} else {
+			feed = feed[:limit]
+		}
+	}
+
+	newer := cursor != nil && cursor.Newer
+	page := &FeedPage{Feed: m.toFeedObjects(feed)}
+	if len(feed) == 0 {
+		// Keep the position so clients polling for newer posts can retry.
+		if newer {
+			page.Prev = raw
+		}
+		return page
+	}
+	if more || newer {
+		page.Next = encodeCursor(cursorOlder, &feed[len(feed)-1])
+	}
+	page.Prev = encodeCursor(cursorNewer, &feed[0])
+	return page
+}
@@ -0,0 +1,171 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/pubsub"
+	"github.com/ava-labs/hypersdk/rpc"
+	nrpc "github.com/nuklai/nuklaivm/rpc"
+	"go.uber.org/zap"
+)
+
+// ErrNetworkMismatch is returned when switching to an RPC endpoint that
+// serves another network or chain without forcing it.
+var ErrNetworkMismatch = errors.New("RPC endpoint serves a different network or chain")
+
+// endpoint is a Nuklai RPC endpoint and the chain it serves.
+type endpoint struct {
+	url       string
+	cli       *rpc.JSONRPCClient
+	ncli      *nrpc.JSONRPCClient
+	networkID uint32
+	subnetID  ids.ID
+	chainID   ids.ID
+}
+
+// dialEndpoint fetches the network served by the Nuklai RPC at [url].
+func dialEndpoint(ctx context.Context, url string) (*endpoint, error) {
+	cli := rpc.NewJSONRPCClient(url)
+	networkID, subnetID, chainID, err := cli.Network(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch network details: %w", err)
+	}
+	return &endpoint{
+		url:       url,
+		cli:       cli,
+		ncli:      nrpc.NewJSONRPCClient(url, networkID, chainID),
+		networkID: networkID,
+		subnetID:  subnetID,
+		chainID:   chainID,
+	}, nil
+}
+
+// useEndpoint makes [e] the endpoint blocks are read from. It must only be
+// called before Run or by the Run loop, which owns the clients.
+func (m *Manager) useEndpoint(e *endpoint) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.rpcURL = e.url
+	m.cli = e.cli
+	m.ncli = e.ncli
+	m.networkID = e.networkID
+	m.subnetID = e.subnetID
+	m.chainID = e.chainID
+}
+
+// rpcSwitch asks the Run loop to switch to another endpoint. done is closed
+// once the switch is made.
+type rpcSwitch struct {
+	endpoint *endpoint
+	done     chan struct{}
+}
+
+// blockMessage is a block read from a stream, or the error that ended it.
+type blockMessage struct {
+	blk     *chain.StatefulBlock
+	results []*chain.Result
+	err     error
+}
+
+// stream reads accepted blocks from the websocket of an endpoint.
+type stream struct {
+	scli   *rpc.WebSocketClient
+	parser chain.Parser
+	blocks chan blockMessage
+	cancel context.CancelFunc
+}
+
+// openStream connects to the websocket of the current endpoint and starts
+// reading blocks from it.
+func (m *Manager) openStream(ctx context.Context) (*stream, error) {
+	parser, err := m.ncli.Parser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parser: %w", err)
+	}
+	scli, err := rpc.NewWebSocketClient(m.rpcURL, rpc.DefaultHandshakeTimeout, pubsub.MaxPendingMessages, pubsub.MaxReadMessageSize)
+	if err != nil {
+		m.log.Warn("Failed to connect to RPC", zap.String("uri", m.rpcURL), zap.Error(err))
+		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
+	}
+	if err := scli.RegisterBlocks(); err != nil {
+		m.log.Warn("Failed to register for blocks", zap.String("uri", m.rpcURL), zap.Error(err))
+		scli.Close()
+		return nil, fmt.Errorf("failed to register for blocks: %w", err)
+	}
+	m.log.Info("Connected to RPC and registered for blocks", zap.String("uri", m.rpcURL))
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &stream{scli: scli, parser: parser, blocks: make(chan blockMessage), cancel: cancel}
+	go func() {
+		defer close(s.blocks)
+		for {
+			blk, results, _, err := scli.ListenBlock(ctx, parser)
+			select {
+			case s.blocks <- blockMessage{blk, results, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// close stops reading blocks, waits for the reader to exit and closes the
+// websocket.
+func (s *stream) close() {
+	s.cancel()
+	for range s.blocks {
+	}
+	_ = s.scli.Close()
+}
+
+// UpdateNuklaiRPC switches the feed to the Nuklai RPC at [newNuklaiRPCUrl].
+// The switch is made by the Run loop between blocks, after which missed
+// blocks are caught up from the new endpoint. Switching to an endpoint on
+// another network or chain fails with ErrNetworkMismatch unless [force] is
+// set.
+func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string, force bool) error {
+	e, err := dialEndpoint(ctx, newNuklaiRPCUrl)
+	if err != nil {
+		m.log.Error("Failed to fetch network details", zap.String("uri", newNuklaiRPCUrl), zap.Error(err))
+		return err
+	}
+
+	m.l.RLock()
+	oldURL, networkID, chainID := m.rpcURL, m.networkID, m.chainID
+	m.l.RUnlock()
+	if e.networkID != networkID || e.chainID != chainID {
+		if !force {
+			return fmt.Errorf("%w: network %d chain %s, indexing network %d chain %s", ErrNetworkMismatch, e.networkID, e.chainID, networkID, chainID)
+		}
+		m.log.Warn("Forcing switch to an RPC on a different network or chain",
+			zap.Uint32("networkID", e.networkID),
+			zap.Stringer("chainID", e.chainID),
+		)
+	}
+
+	m.log.Info("Updating Nuklai RPC URL", zap.String("oldURL", oldURL), zap.String("newURL", newNuklaiRPCUrl))
+	req := &rpcSwitch{endpoint: e, done: make(chan struct{})}
+	select {
+	case m.switches <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-req.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
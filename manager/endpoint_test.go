@@ -0,0 +1,75 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/timer"
+	"github.com/ava-labs/hypersdk/rpc"
+	"github.com/nuklai/nuklai-feed/database"
+	nrpc "github.com/nuklai/nuklaivm/rpc"
+)
+
+// unreachableEndpoint returns an endpoint whose RPC fails every request.
+func unreachableEndpoint(t *testing.T, chainID ids.ID) *endpoint {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	return &endpoint{
+		url:     srv.URL,
+		cli:     rpc.NewJSONRPCClient(srv.URL),
+		ncli:    nrpc.NewJSONRPCClient(srv.URL, 0, chainID),
+		chainID: chainID,
+	}
+}
+
+func TestRunSwitchesAfterFailedConnection(t *testing.T) {
+	m := newTestManager(t, database.NewMemoryDB())
+	m.t = timer.NewTimer(m.updateFee)
+	m.useEndpoint(unreachableEndpoint(t, ids.GenerateTestID()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	// The switch is made although the first endpoint never connected.
+	next := unreachableEndpoint(t, ids.GenerateTestID())
+	req := &rpcSwitch{endpoint: next, done: make(chan struct{})}
+	select {
+	case m.switches <- req:
+	case err := <-done:
+		t.Fatalf("Run returned before switching: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not accept the switch")
+	}
+	select {
+	case <-req.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not make the switch")
+	}
+	if _, chainID := m.currentChain(); chainID != next.chainID.String() {
+		t.Fatalf("indexing chain %s, want %s", chainID, next.chainID)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"math"
+	"time"
+
+	fconfig "github.com/nuklai/nuklai-feed/config"
+)
+
+// FeeController adjusts the fee required to post once per epoch based on how
+// many posts were accepted during the epoch. It is not safe for concurrent
+// use.
+type FeeController struct {
+	minFee           uint64
+	feeDelta         uint64
+	messagesPerEpoch int
+	epochDuration    time.Duration
+	now              func() time.Time
+
+	fee           uint64
+	epochStart    time.Time
+	epochMessages int
+}
+
+// NewFeeController creates a FeeController starting at [config.MinFee].
+// [now] is used to read the current time.
+func NewFeeController(config *fconfig.Config, now func() time.Time) *FeeController {
+	f := &FeeController{
+		minFee:           config.MinFee,
+		feeDelta:         config.FeeDelta,
+		messagesPerEpoch: config.MessagesPerEpoch,
+		epochDuration:    time.Duration(config.TargetDurationPerEpoch) * time.Second,
+		now:              now,
+	}
+	f.Reset()
+	return f
+}
+
+// Reset sets the fee back to the minimum and starts a new epoch.
+func (f *FeeController) Reset() {
+	f.fee = f.minFee
+	f.epochStart = f.now()
+	f.epochMessages = 0
+}
+
+// Restore resumes an epoch that started at [epochStart] with [messages]
+// accepted posts, charging [fee].
+func (f *FeeController) Restore(fee uint64, epochStart time.Time, messages int) {
+	f.fee = fee
+	f.epochStart = epochStart
+	f.epochMessages = messages
+}
+
+// Fee returns the fee currently required to post.
+func (f *FeeController) Fee() uint64 {
+	return f.fee
+}
+
+// EpochStart returns when the current epoch started.
+func (f *FeeController) EpochStart() time.Time {
+	return f.epochStart
+}
+
+// EpochMessages returns the number of posts accepted in the current epoch.
+func (f *FeeController) EpochMessages() int {
+	return f.epochMessages
+}
+
+// UntilEpochEnd returns how long is left in the current epoch.
+func (f *FeeController) UntilEpochEnd() time.Duration {
+	remaining := f.epochStart.Add(f.epochDuration).Sub(f.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// RecordMessage counts an accepted post towards the current epoch.
+func (f *FeeController) RecordMessage() {
+	f.epochMessages++
+}
+
+// Rotate ends the current epoch if it has elapsed and adjusts the fee for the
+// next one. It returns false if the epoch has not ended yet.
+//
+// The fee is raised by FeeDelta for every MessagesPerEpoch posts over the
+// target, and lowered by FeeDelta (but never below MinFee) when fewer than
+// half of the target posts were accepted. If several epochs elapsed, for
+// example while the feed was offline, the later ones had no posts and each
+// lowers the fee. The next epoch starts where the elapsed ones end, so
+// epochs do not drift with late rotations.
+func (f *FeeController) Rotate() bool {
+	now := f.now()
+	if now.Before(f.epochStart.Add(f.epochDuration)) {
+		return false
+	}
+
+	elapsed := uint64(1)
+	if f.epochDuration > 0 {
+		elapsed = uint64(now.Sub(f.epochStart) / f.epochDuration)
+	}
+	switch target := f.messagesPerEpoch; {
+	case target > 0 && f.epochMessages > target:
+		steps := uint64((f.epochMessages - 1) / target)
+		f.fee = addFee(f.fee, mulFee(f.feeDelta, steps))
+	case f.epochMessages*2 < target:
+		f.lowerFee(1)
+	}
+	if f.messagesPerEpoch > 0 {
+		f.lowerFee(elapsed - 1)
+	}
+
+	if f.epochDuration > 0 {
+		f.epochStart = f.epochStart.Add(time.Duration(elapsed) * f.epochDuration)
+	} else {
+		f.epochStart = now
+	}
+	f.epochMessages = 0
+	return true
+}
+
+// lowerFee lowers the fee by FeeDelta [steps] times, but never below MinFee.
+func (f *FeeController) lowerFee(steps uint64) {
+	if steps == 0 {
+		return
+	}
+	delta := mulFee(f.feeDelta, steps)
+	if f.fee >= delta && f.fee-delta > f.minFee {
+		f.fee -= delta
+	} else {
+		f.fee = f.minFee
+	}
+}
+
+func addFee(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func mulFee(a, b uint64) uint64 {
+	if b != 0 && a > math.MaxUint64/b {
+		return math.MaxUint64
+	}
+	return a * b
+}
@@ -0,0 +1,401 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/timer"
+	"github.com/ava-labs/hypersdk/chain"
+	fconfig "github.com/nuklai/nuklai-feed/config"
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// fakeClock is a clock that only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+const testEpoch = time.Minute
+
+func newTestFeeController() (*FeeController, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	config := &fconfig.Config{
+		MinFee:                 100,
+		FeeDelta:               10,
+		MessagesPerEpoch:       10,
+		TargetDurationPerEpoch: int64(testEpoch / time.Second),
+	}
+	return NewFeeController(config, clock.Now), clock
+}
+
+// endEpoch records [messages] posts and rotates once the epoch has elapsed.
+func endEpoch(t *testing.T, f *FeeController, clock *fakeClock, messages int) {
+	t.Helper()
+
+	for i := 0; i < messages; i++ {
+		f.RecordMessage()
+	}
+	clock.Advance(f.UntilEpochEnd())
+	if !f.Rotate() {
+		t.Fatal("epoch did not rotate after elapsing")
+	}
+}
+
+func TestFeeRotateBeforeEpochEnd(t *testing.T) {
+	f, clock := newTestFeeController()
+	clock.Advance(testEpoch - time.Second)
+	if f.Rotate() {
+		t.Fatal("rotated before the epoch ended")
+	}
+	if got := f.UntilEpochEnd(); got != time.Second {
+		t.Fatalf("UntilEpochEnd = %s, want 1s", got)
+	}
+}
+
+func TestFeeAdjustments(t *testing.T) {
+	tests := []struct {
+		name     string
+		fee      uint64
+		messages int
+		want     uint64
+	}{
+		{"at target", 150, 10, 150},
+		{"half of target", 150, 5, 150},
+		{"one over target", 150, 11, 160},
+		{"twice the target", 150, 20, 160},
+		{"proportional raise", 150, 35, 180},
+		{"lowered under half of target", 150, 4, 140},
+		{"lowered without posts", 150, 0, 140},
+		{"lowered to the floor", 105, 0, 100},
+		{"kept at the floor", 100, 0, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, clock := newTestFeeController()
+			f.Restore(tt.fee, clock.now, 0)
+			endEpoch(t, f, clock, tt.messages)
+			if got := f.Fee(); got != tt.want {
+				t.Fatalf("fee = %d, want %d", got, tt.want)
+			}
+			if f.EpochMessages() != 0 {
+				t.Fatalf("epoch messages = %d after rotation", f.EpochMessages())
+			}
+		})
+	}
+}
+
+func TestFeeRaiseSaturates(t *testing.T) {
+	f, clock := newTestFeeController()
+	f.Restore(math.MaxUint64-5, clock.now, 0)
+	endEpoch(t, f, clock, 100)
+	if got := f.Fee(); got != math.MaxUint64 {
+		t.Fatalf("fee = %d, want MaxUint64", got)
+	}
+}
+
+func TestFeeArithmeticSaturates(t *testing.T) {
+	if got := addFee(math.MaxUint64, 1); got != math.MaxUint64 {
+		t.Fatalf("addFee overflowed to %d", got)
+	}
+	if got := addFee(math.MaxUint64-1, 1); got != math.MaxUint64 {
+		t.Fatalf("addFee = %d, want MaxUint64", got)
+	}
+	if got := addFee(1, 2); got != 3 {
+		t.Fatalf("addFee = %d, want 3", got)
+	}
+	if got := mulFee(math.MaxUint64/2+1, 2); got != math.MaxUint64 {
+		t.Fatalf("mulFee overflowed to %d", got)
+	}
+	if got := mulFee(math.MaxUint64, 0); got != 0 {
+		t.Fatalf("mulFee = %d, want 0", got)
+	}
+	if got := mulFee(7, 6); got != 42 {
+		t.Fatalf("mulFee = %d, want 42", got)
+	}
+}
+
+func TestFeeRotateAfterSeveralEpochs(t *testing.T) {
+	f, clock := newTestFeeController()
+	start := f.EpochStart()
+	f.Restore(200, start, 0)
+	for i := 0; i < 35; i++ {
+		f.RecordMessage()
+	}
+
+	// Three and a half epochs elapse before the rotation: the first one had
+	// 35 posts and the two after it none.
+	clock.Advance(3*testEpoch + testEpoch/2)
+	if !f.Rotate() {
+		t.Fatal("epoch did not rotate")
+	}
+	if got := f.Fee(); got != 210 {
+		t.Fatalf("fee = %d, want 210", got)
+	}
+	if got, want := f.EpochStart(), start.Add(3*testEpoch); !got.Equal(want) {
+		t.Fatalf("epoch start = %s, want %s", got, want)
+	}
+	if got := f.UntilEpochEnd(); got != testEpoch/2 {
+		t.Fatalf("UntilEpochEnd = %s, want %s", got, testEpoch/2)
+	}
+	if f.Rotate() {
+		t.Fatal("rotated twice for the same epochs")
+	}
+}
+
+func TestFeeRotateAfterLongDowntime(t *testing.T) {
+	f, clock := newTestFeeController()
+	f.Restore(1_000, f.EpochStart(), 0)
+	clock.Advance(24 * time.Hour)
+	if !f.Rotate() {
+		t.Fatal("epoch did not rotate")
+	}
+	if got := f.Fee(); got != 100 {
+		t.Fatalf("fee = %d, want the MinFee floor", got)
+	}
+	if got := f.UntilEpochEnd(); got != testEpoch {
+		t.Fatalf("UntilEpochEnd = %s, want %s", got, testEpoch)
+	}
+}
+
+func TestFeeReset(t *testing.T) {
+	f, clock := newTestFeeController()
+	endEpoch(t, f, clock, 50)
+	clock.Advance(time.Second)
+	f.RecordMessage()
+	f.Reset()
+	if f.Fee() != 100 || f.EpochMessages() != 0 || !f.EpochStart().Equal(clock.now) {
+		t.Fatalf("reset left fee %d, %d messages, start %s", f.Fee(), f.EpochMessages(), f.EpochStart())
+	}
+}
+
+// failingRotationDB fails to rotate fee epochs while [fail] is set.
+type failingRotationDB struct {
+	*database.MemoryDB
+	fail bool
+}
+
+func (db *failingRotationDB) RotateFeeEpoch(closed, opened *database.FeeEpoch) error {
+	if db.fail {
+		return errors.New("database unavailable")
+	}
+	return db.MemoryDB.RotateFeeEpoch(closed, opened)
+}
+
+func TestUpdateFeeRetriesFailedRotation(t *testing.T) {
+	db := &failingRotationDB{MemoryDB: database.NewMemoryDB(), fail: true}
+	m := newTestManager(t, db)
+	f, clock := newTestFeeController()
+	m.fee = f
+	m.t = timer.NewTimer(m.updateFee)
+	start := f.EpochStart()
+	if err := m.saveFeeEpoch(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 25; i++ {
+		f.RecordMessage()
+	}
+	clock.Advance(testEpoch)
+
+	m.updateFee()
+	if f.Fee() != 100 || !f.EpochStart().Equal(start) || f.EpochMessages() != 25 {
+		t.Fatalf("failed rotation was applied: fee %d, start %s, %d messages", f.Fee(), f.EpochStart(), f.EpochMessages())
+	}
+	current, ok, err := db.GetCurrentFeeEpoch()
+	if err != nil || !ok || current.EpochStart != start.Unix() {
+		t.Fatalf("running epoch = %+v (%t, %v), want the one started at %d", current, ok, err, start.Unix())
+	}
+
+	// The epoch keeps running until the retry succeeds.
+	f.RecordMessage()
+	db.fail = false
+	m.updateFee()
+	if f.Fee() != 120 || f.EpochMessages() != 0 {
+		t.Fatalf("fee = %d with %d messages after rotation, want 120 and 0", f.Fee(), f.EpochMessages())
+	}
+	next := start.Add(testEpoch).Unix()
+	current, ok, err = db.GetCurrentFeeEpoch()
+	if err != nil || !ok || current.EpochStart != next || current.Fee != 120 {
+		t.Fatalf("running epoch = %+v (%t, %v), want the one started at %d", current, ok, err, next)
+	}
+	history, err := db.GetFeeHistory(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].EpochEnd != next || history[1].Messages != 26 {
+		t.Fatalf("fee history = %+v, want the closed epoch with 26 messages", history)
+	}
+}
+
+// failingIndexDB fails to index blocks while [fail] is set. Fee epochs can
+// only be saved with a block.
+type failingIndexDB struct {
+	*database.MemoryDB
+	fail bool
+}
+
+func (*failingIndexDB) SaveFeeEpoch(*database.FeeEpoch) error {
+	return errors.New("fee epoch saved outside of a block")
+}
+
+func (db *failingIndexDB) IndexBlock(blk *database.BlockObject, feeds []database.FeedObject, reactions []database.ReactionObject, revisions []database.RevisionObject, epoch *database.FeeEpoch) error {
+	if db.fail {
+		return errors.New("database unavailable")
+	}
+	return db.MemoryDB.IndexBlock(blk, feeds, reactions, revisions, epoch)
+}
+
+func TestProcessBlockSavesFeeEpoch(t *testing.T) {
+	db := &failingIndexDB{MemoryDB: database.NewMemoryDB()}
+	m := newTestManager(t, db)
+	f, _ := newTestFeeController()
+	m.fee = f
+	if err := db.MemoryDB.SaveFeeEpoch(feeEpoch(f, 0)); err != nil {
+		t.Fatal(err)
+	}
+	checkEpochMessages := func(want int) {
+		t.Helper()
+		current, ok, err := db.GetCurrentFeeEpoch()
+		if err != nil || !ok {
+			t.Fatalf("no running epoch (%t, %v)", ok, err)
+		}
+		if f.EpochMessages() != want || current.Messages != want {
+			t.Fatalf("epoch has %d messages, %d saved, want %d", f.EpochMessages(), current.Messages, want)
+		}
+	}
+
+	err := processTxs(t, m, 1, testTransfer(t, m, 100, "first"), testTransfer(t, m, 100, "second"), testTransfer(t, m, 99, "underpaid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEpochMessages(2)
+
+	// Messages of a block that could not be indexed are not counted, so the
+	// block can be retried.
+	db.fail = true
+	if err := processTxs(t, m, 2, testTransfer(t, m, 100, "third")); err == nil {
+		t.Fatal("indexing succeeded")
+	}
+	db.fail = false
+	checkEpochMessages(2)
+}
+
+func TestReactionsPayFee(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+	f, _ := newTestFeeController()
+	m.fee = f
+
+	post := testTransfer(t, m, 100, "post")
+	if err := processTxs(t, m, 1, post); err != nil {
+		t.Fatal(err)
+	}
+	memo := `{"reactTo":"` + post.ID().String() + `","reaction":"+1"}`
+	if err := processTxs(t, m, 2, testTransfer(t, m, 99, memo), testTransfer(t, m, 150, memo)); err != nil {
+		t.Fatal(err)
+	}
+
+	totals, err := db.GetReactionTotals([]string{post.ID().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].Count != 1 || totals[0].Tips != 150 {
+		t.Fatalf("reaction totals = %+v, want a single reaction tipping 150", totals)
+	}
+	if f.EpochMessages() != 2 {
+		t.Fatalf("epoch has %d messages, want the post and the paid reaction", f.EpochMessages())
+	}
+}
+
+func TestReorgUncountsMessages(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+			f, clock := newTestFeeController()
+			m.fee = f
+			m.t = timer.NewTimer(m.updateFee)
+			if err := m.saveFeeEpoch(); err != nil {
+				t.Fatal(err)
+			}
+			ingest := func(blk *chain.StatefulBlock) {
+				t.Helper()
+				results := make([]*chain.Result, len(blk.Txs))
+				for i := range results {
+					results[i] = &chain.Result{Success: true}
+				}
+				if err := m.handleBlock(context.Background(), nil, blk, results); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkEpoch := func(epochStart int64, want int) {
+				t.Helper()
+				history, err := db.GetFeeHistory(10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, epoch := range history {
+					if epoch.EpochStart == epochStart {
+						if epoch.Messages != want {
+							t.Fatalf("epoch started at %d has %d messages, want %d", epochStart, epoch.Messages, want)
+						}
+						return
+					}
+				}
+				t.Fatalf("no epoch started at %d in %+v", epochStart, history)
+			}
+
+			blocks := testBlocks(t, 1)
+			ingest(blocks[0])
+			post := testTransfer(t, m, 100, "post")
+			blk1 := &chain.StatefulBlock{Prnt: blockID(t, blocks[0]), Tmstmp: 1000, Hght: 1, Txs: []*chain.Transaction{post, testTransfer(t, m, 100, "second")}}
+			ingest(blk1)
+			closed := f.EpochStart().Unix()
+
+			// Block 2 is indexed in the next epoch, block 3 in the same one.
+			clock.Advance(testEpoch)
+			m.updateFee()
+			running := f.EpochStart().Unix()
+			blk2 := &chain.StatefulBlock{Prnt: blockID(t, blk1), Tmstmp: 2000, Hght: 2, Txs: []*chain.Transaction{
+				testTransfer(t, m, 100, "third"),
+				testTransfer(t, m, 100, `{"reactTo":"`+post.ID().String()+`","reaction":"+1"}`),
+			}}
+			ingest(blk2)
+			blk3 := &chain.StatefulBlock{Prnt: blockID(t, blk2), Tmstmp: 3000, Hght: 3, Txs: []*chain.Transaction{testTransfer(t, m, 100, "fourth")}}
+			ingest(blk3)
+			checkEpoch(closed, 2)
+			checkEpoch(running, 3)
+
+			// A competing block 3 only takes its own messages out of the
+			// running epoch.
+			fork3 := &chain.StatefulBlock{Prnt: blockID(t, blk2), Tmstmp: 3500, Hght: 3}
+			ingest(fork3)
+			checkEpoch(running, 2)
+			if f.EpochMessages() != 2 {
+				t.Fatalf("running epoch has %d messages, want 2", f.EpochMessages())
+			}
+
+			// Rolling back blocks of a closed epoch leaves its count as it was
+			// when the fee was adjusted.
+			fork1 := &chain.StatefulBlock{Prnt: blockID(t, blocks[0]), Tmstmp: 1500, Hght: 1}
+			ingest(fork1)
+			checkEpoch(closed, 2)
+			checkEpoch(running, 0)
+			if f.EpochMessages() != 0 {
+				t.Fatalf("running epoch has %d messages, want 0", f.EpochMessages())
+			}
+		})
+	}
+}
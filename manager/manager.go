@@ -5,17 +5,17 @@ package manager
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/timer"
+	"github.com/ava-labs/hypersdk/chain"
 	"github.com/ava-labs/hypersdk/codec"
-	"github.com/ava-labs/hypersdk/pubsub"
 	"github.com/ava-labs/hypersdk/rpc"
 	"github.com/ava-labs/hypersdk/utils"
 	fconfig "github.com/nuklai/nuklai-feed/config"
@@ -26,9 +26,31 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrFinalizedConflict is returned when a block conflicts with a block that
+// has already been marked final.
+var ErrFinalizedConflict = errors.New("block conflicts with finalized block")
+
+// ErrFeedNotFound is returned when no post matches a lookup.
+var ErrFeedNotFound = errors.New("feed not found")
+
+// ErrChainMismatch is returned when a query targets a subnet or chain other
+// than the one the feed is indexing.
+var ErrChainMismatch = errors.New("subnet or chain does not match feed")
+
 type FeedContent struct {
 	Message string `json:"message"`
 	URL     string `json:"url"`
+	// ReplyTo makes the post a reply to the first post of a transaction, or
+	// to a single post if it is followed by a slash and the action index.
+	ReplyTo string `json:"replyTo,omitempty"`
+	// ReactTo makes the memo a reaction to a post, referenced like ReplyTo,
+	// instead of a post. Reactions carry no message.
+	ReactTo  string `json:"reactTo,omitempty"`
+	Reaction string `json:"reaction,omitempty"`
+	// Edit replaces the message and URL of a post of the sender, referenced
+	// like ReplyTo, and Delete retracts one. Neither makes a new post.
+	Edit   string `json:"edit,omitempty"`
+	Delete string `json:"delete,omitempty"`
 }
 
 type FeedObject struct {
@@ -39,233 +61,735 @@ type FeedObject struct {
 	Timestamp int64  `json:"timestamp"`
 	Fee       uint64 `json:"fee"`
 
-	Content *FeedContent `json:"content"`
+	// ActionIndex is the index of the transfer within the transaction, so a
+	// transaction can make several posts.
+	ActionIndex int `json:"actionIndex"`
+
+	// BlockID and Height identify the block that included the post. Status
+	// is "pending" until that block is final.
+	BlockID ids.ID `json:"blockID"`
+	Height  uint64 `json:"height"`
+	Status  string `json:"status"`
+
+	// Parent and Root are the post a reply answers and the first post of
+	// its thread. Both are nil for other posts.
+	Parent *PostRef `json:"parent,omitempty"`
+	Root   *PostRef `json:"root,omitempty"`
+
+	// Encoding is the name of the decoder of the memo, see MemoDecoder.
+	Encoding string       `json:"encoding"`
+	Content  *FeedContent `json:"content"`
+
+	// Edited is set on posts whose author changed their content, which is
+	// the latest version. Deleted is set on posts retracted by their author,
+	// which carry no content.
+	Edited  bool `json:"edited"`
+	Deleted bool `json:"deleted"`
+
+	// Reactions counts the reactions to the post by kind, and Tips sums the
+	// value they transferred.
+	Reactions map[string]int `json:"reactions,omitempty"`
+	Tips      uint64         `json:"tips"`
+}
+
+// FeeEpoch describes the fee charged during an epoch. EpochEnd is zero for
+// the current epoch.
+type FeeEpoch struct {
+	EpochStart int64  `json:"epochStart"`
+	EpochEnd   int64  `json:"epochEnd"`
+	Fee        uint64 `json:"fee"`
+	Messages   int    `json:"messages"`
 }
 
 type Manager struct {
 	log    logging.Logger
 	config *fconfig.Config
 
-	ncli     *nrpc.JSONRPCClient
-	subnetID ids.ID
-	chainID  ids.ID
-
-	l             sync.RWMutex
-	t             *timer.Timer
-	epochStart    int64
-	epochMessages int
-	feeAmount     uint64
+	// The clients of the current endpoint are only replaced by the Run loop,
+	// under l. rpcURL, networkID, subnetID and chainID are read under l.
+	rpcURL    string
+	cli       *rpc.JSONRPCClient
+	ncli      *nrpc.JSONRPCClient
+	networkID uint32
+	subnetID  ids.ID
+	chainID   ids.ID
+	switches  chan *rpcSwitch
+
+	l       sync.RWMutex
+	t       *timer.Timer
+	fee     *FeeController
+	fetcher BlockFetcher
 
 	feed       []*FeedObject
+	memos      *MemoDecoders
+	subs       *broadcaster
+	banned     map[string]struct{}
 	cancelFunc context.CancelFunc
 
-	db *database.DB
+	db database.Store
 }
 
-func New(logger logging.Logger, config *fconfig.Config, db *sql.DB) (*Manager, error) {
+func New(logger logging.Logger, config *fconfig.Config, db database.Store) (*Manager, error) {
 	ctx, cancel := context.WithCancel(context.Background())
-	cli := rpc.NewJSONRPCClient(config.NuklaiRPC)
-	networkID, subnetID, chainID, err := cli.Network(ctx)
+	e, err := dialEndpoint(ctx, config.NuklaiRPC)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
-	ncli := nrpc.NewJSONRPCClient(config.NuklaiRPC, networkID, chainID)
 
-	dbInstance, err := database.NewDB(db)
+	m := &Manager{log: logger, config: config, switches: make(chan *rpcSwitch), feed: []*FeedObject{}, memos: NewMemoDecoders(), subs: newBroadcaster(), cancelFunc: cancel, db: db}
+	m.useEndpoint(e)
+	if len(config.BlockArchiveURL) > 0 {
+		m.fetcher = NewArchiveFetcher(config.BlockArchiveURL)
+	}
+	m.fee = NewFeeController(config, time.Now)
+	epoch, ok, err := db.GetCurrentFeeEpoch()
 	if err != nil {
 		cancel()
 		return nil, err
 	}
-	m := &Manager{log: logger, config: config, ncli: ncli, subnetID: subnetID, chainID: chainID, feed: []*FeedObject{}, cancelFunc: cancel, db: dbInstance}
-	m.epochStart = time.Now().Unix()
-	m.feeAmount = m.config.MinFee
+	if ok {
+		m.fee.Restore(epoch.Fee, time.Unix(epoch.EpochStart, 0), epoch.Messages)
+	} else if err := m.saveFeeEpoch(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := m.loadBannedAuthors(); err != nil {
+		cancel()
+		return nil, err
+	}
 	m.t = timer.NewTimer(m.updateFee)
 	m.log.Info("feed initialized",
-		zap.Uint32("network ID", networkID),
-		zap.String("subnet ID", subnetID.String()),
-		zap.String("chain ID", chainID.String()),
+		zap.Uint32("network ID", e.networkID),
+		zap.String("subnet ID", e.subnetID.String()),
+		zap.String("chain ID", e.chainID.String()),
 		zap.String("address", m.config.Recipient),
-		zap.String("fee", utils.FormatBalance(m.feeAmount, nconsts.Decimals)),
+		zap.String("fee", utils.FormatBalance(m.fee.Fee(), nconsts.Decimals)),
 	)
 
 	return m, nil
 }
 
-func (m *Manager) saveFeed(feed *FeedObject) error {
-	content, err := json.Marshal(feed.Content)
-	if err != nil {
-		m.log.Error("Failed to marshal feed content", zap.Error(err))
-		return fmt.Errorf("failed to marshal feed content: %w", err)
-	}
-	err = m.db.SaveFeed(&database.FeedObject{
-		TxID:      feed.TxID.String(),
-		SubnetID:  feed.SubnetID,
-		ChainID:   feed.ChainID,
-		Address:   feed.Address,
-		Timestamp: feed.Timestamp,
-		Fee:       feed.Fee,
-		Content:   string(content),
-	})
-	if err != nil {
-		m.log.Error("Failed to save feed to database", zap.Error(err))
+// toDatabaseFeed converts [feed] to the form stored in the database.
+func toDatabaseFeed(feed *FeedObject) *database.FeedObject {
+	dbFeed := &database.FeedObject{
+		TxID:        feed.TxID.String(),
+		ActionIndex: feed.ActionIndex,
+		SubnetID:    feed.SubnetID,
+		ChainID:     feed.ChainID,
+		Address:     feed.Address,
+		Timestamp:   feed.Timestamp,
+		Fee:         feed.Fee,
+		Message:     feed.Content.Message,
+		URL:         feed.Content.URL,
+		Encoding:    feed.Encoding,
+		BlockID:     feed.BlockID.String(),
+		Height:      feed.Height,
+		Status:      feed.Status,
 	}
-	return err
+	if feed.Parent != nil {
+		dbFeed.ParentTxID, dbFeed.ParentActionIndex = feed.Parent.TxID.String(), feed.Parent.ActionIndex
+		dbFeed.RootTxID, dbFeed.RootActionIndex = feed.Root.TxID.String(), feed.Root.ActionIndex
+	}
+	return dbFeed
 }
 
-func (m *Manager) getLastFeeds(n int) ([]*FeedObject, error) {
-	feeds, err := m.db.GetLastFeeds(n)
+// toFeedObject converts a stored feed. It fails if the row is malformed.
+func toFeedObject(feed *database.FeedObject) (*FeedObject, error) {
+	txID, err := ids.FromString(feed.TxID)
 	if err != nil {
-		m.log.Error("Failed to get last feeds from database", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("invalid TxID: %w", err)
 	}
-	var feedObjects []*FeedObject
-	for _, feed := range feeds {
-		var content FeedContent
-		if err := json.Unmarshal([]byte(feed.Content), &content); err != nil {
-			m.log.Error("Failed to unmarshal feed content", zap.Error(err))
-			return nil, err
+	// Feeds indexed before block tracking have no block ID.
+	var blockID ids.ID
+	if len(feed.BlockID) > 0 {
+		blockID, err = ids.FromString(feed.BlockID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BlockID: %w", err)
 		}
-		txID, err := ids.FromString(feed.TxID)
+	}
+	// Posts are never indexed without a message, so an empty one means the
+	// content could not be migrated unless the post was deleted.
+	if len(feed.Message) == 0 && !feed.Deleted {
+		return nil, errors.New("empty message")
+	}
+	parent, err := newPostRef(feed.ParentTxID, feed.ParentActionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent TxID: %w", err)
+	}
+	root, err := newPostRef(feed.RootTxID, feed.RootActionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root TxID: %w", err)
+	}
+	if (parent == nil) != (root == nil) {
+		return nil, errors.New("reply without both parent and root")
+	}
+	content := &FeedContent{
+		Message: feed.Message,
+		URL:     feed.URL,
+	}
+	if parent != nil {
+		content.ReplyTo = parent.String()
+	}
+	return &FeedObject{
+		SubnetID:    feed.SubnetID,
+		ChainID:     feed.ChainID,
+		Address:     feed.Address,
+		TxID:        txID,
+		Timestamp:   feed.Timestamp,
+		Fee:         feed.Fee,
+		ActionIndex: feed.ActionIndex,
+		BlockID:     blockID,
+		Height:      feed.Height,
+		Status:      feed.Status,
+		Parent:      parent,
+		Root:        root,
+		Encoding:    feed.Encoding,
+		Content:     content,
+		Edited:      feed.Revision > 0 && !feed.Deleted,
+		Deleted:     feed.Deleted,
+	}, nil
+}
+
+// toFeedObjects converts stored feeds. Malformed rows are logged and skipped
+// so that one bad row does not fail a whole page.
+func (m *Manager) toFeedObjects(feeds []database.FeedObject) []*FeedObject {
+	feedObjects := make([]*FeedObject, 0, len(feeds))
+	for i := range feeds {
+		feed, err := toFeedObject(&feeds[i])
 		if err != nil {
-			m.log.Error("Failed to parse TxID from string", zap.Error(err))
-			return nil, err
+			m.log.Warn("Skipping malformed feed",
+				zap.String("TxID", feeds[i].TxID),
+				zap.Int("action", feeds[i].ActionIndex),
+				zap.Error(err),
+			)
+			continue
 		}
-		feedObjects = append(feedObjects, &FeedObject{
-			SubnetID:  feed.SubnetID,
-			ChainID:   feed.ChainID,
-			Address:   feed.Address,
-			TxID:      txID,
-			Timestamp: feed.Timestamp,
-			Fee:       feed.Fee,
-			Content:   &content,
-		})
+		feedObjects = append(feedObjects, feed)
 	}
-	return feedObjects, nil
+	m.addReactions(feedObjects)
+	return feedObjects
 }
 
-func (m *Manager) appendFeed(feed *FeedObject) {
-	m.log.Info("Appending new feed", zap.String("TxID", feed.TxID.String()))
-	if err := m.saveFeed(feed); err != nil {
-		m.log.Error("Failed to save feed", zap.Error(err))
+// feeRetryInterval is how long to wait before retrying a fee epoch rotation
+// that could not be saved.
+const feeRetryInterval = 10 * time.Second
+
+// feeEpoch returns the current epoch of [f] as stored. A non-zero [end]
+// closes it.
+func feeEpoch(f *FeeController, end int64) *database.FeeEpoch {
+	return &database.FeeEpoch{
+		EpochStart: f.EpochStart().Unix(),
+		EpochEnd:   end,
+		Fee:        f.Fee(),
+		Messages:   f.EpochMessages(),
 	}
 }
 
+// saveFeeEpoch persists the current fee epoch.
+func (m *Manager) saveFeeEpoch() error {
+	err := m.db.SaveFeeEpoch(feeEpoch(m.fee, 0))
+	if err != nil {
+		m.log.Error("Failed to save fee epoch", zap.Error(err))
+	}
+	return err
+}
+
+// updateFee rotates the fee epoch once it has elapsed. The rotation is only
+// applied once the closed and opened epochs are saved; otherwise the current
+// epoch keeps running and the rotation is retried.
 func (m *Manager) updateFee() {
 	m.l.Lock()
 	defer m.l.Unlock()
 
-	now := time.Now().Unix()
-	if now-m.epochStart < m.config.TargetDurationPerEpoch/2 {
+	next := *m.fee
+	if !next.Rotate() {
+		m.t.SetTimeoutIn(m.fee.UntilEpochEnd())
+		return
+	}
+	// Close the current epoch where the next one starts so its final message
+	// count is kept in the fee history.
+	closed, opened := feeEpoch(m.fee, next.EpochStart().Unix()), feeEpoch(&next, 0)
+	if err := m.db.RotateFeeEpoch(closed, opened); err != nil {
+		m.log.Error("Failed to rotate fee epoch, retrying", zap.Duration("retryIn", feeRetryInterval), zap.Error(err))
+		m.t.SetTimeoutIn(feeRetryInterval)
 		return
 	}
+	*m.fee = next
+	m.log.Info("Fee updated",
+		zap.Int("epochMessages", closed.Messages),
+		zap.Int("targetMessages", m.config.MessagesPerEpoch),
+		zap.Int64("epochStart", opened.EpochStart),
+		zap.Uint64("feeAmount", opened.Fee),
+	)
+	m.t.SetTimeoutIn(m.fee.UntilEpochEnd())
+}
 
-	if m.feeAmount > m.config.MinFee && m.epochMessages == 0 {
-		m.feeAmount -= m.config.FeeDelta
-		m.log.Info("Decreasing message fee", zap.Uint64("fee", m.feeAmount))
+// processBlock indexes every paid post, reaction and revision in [blk] as
+// pending, records the block, advances the block cursor to its height and
+// saves the messages it paid for in the running fee epoch. They are written
+// atomically, so a crash never leaves a block partially
+// indexed.
+func (m *Manager) processBlock(blk *chain.StatefulBlock, blkID ids.ID, results []*chain.Result) error {
+	recipientAddr, err := m.config.RecipientAddress()
+	if err != nil {
+		m.log.Error("Failed to get recipient address", zap.Error(err))
+		return err
 	}
-	m.epochMessages = 0
-	m.epochStart = time.Now().Unix()
-	m.t.SetTimeoutIn(time.Duration(m.config.TargetDurationPerEpoch) * time.Second)
-	m.log.Info("Fee updated", zap.Int64("epochStart", m.epochStart), zap.Uint64("feeAmount", m.feeAmount))
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	var (
+		posts     []*FeedObject
+		feeds     []database.FeedObject
+		reactions []database.ReactionObject
+		revisions = newBlockRevisions()
+		revised   int
+	)
+	for i, tx := range blk.Txs {
+		result := results[i]
+		if !result.Success {
+			continue
+		}
+		for j, act := range tx.Actions {
+			action, ok := act.(*actions.Transfer)
+			if !ok || action.To != recipientAddr {
+				continue
+			}
+
+			fromStr := codec.MustAddressBech32(nconsts.HRP, tx.Auth.Actor())
+			content, encoding, err := m.memos.Decode(action.Memo)
+			if err != nil {
+				m.log.Info("Incoming message could not be parsed or was empty", zap.String("from", fromStr), zap.String("memo", string(action.Memo)), zap.Uint64("payment", action.Value), zap.Error(err))
+				continue
+			}
+
+			if action.Value < m.fee.Fee() {
+				m.log.Info("Incoming message failed or did not pay enough", zap.String("from", fromStr), zap.String("memo", string(action.Memo)), zap.Uint64("payment", action.Value), zap.Uint64("required", m.fee.Fee()))
+				continue
+			}
+
+			// Reactions pay the fee like posts, and their whole value tips the
+			// post.
+			if len(content.ReactTo) > 0 {
+				target, err := m.resolveReaction(content.ReactTo, feeds, revisions)
+				if err != nil {
+					m.log.Info("Incoming reaction does not target a known post", zap.String("from", fromStr), zap.String("reactTo", content.ReactTo), zap.Error(err))
+					continue
+				}
+				m.log.Info("Appending new reaction", zap.Stringer("TxID", tx.ID()), zap.Int("action", j), zap.String("target", target.TxID))
+				reactions = append(reactions, database.ReactionObject{
+					ChainID:           m.chainID.String(),
+					TxID:              tx.ID().String(),
+					ActionIndex:       j,
+					TargetTxID:        target.TxID,
+					TargetActionIndex: target.ActionIndex,
+					Address:           fromStr,
+					Reaction:          content.Reaction,
+					Value:             action.Value,
+					Timestamp:         blk.Tmstmp,
+					Height:            blk.Hght,
+				})
+				continue
+			}
+
+			if len(content.Edit) > 0 || len(content.Delete) > 0 {
+				rev := database.RevisionObject{
+					ChainID:             m.chainID.String(),
+					Action:              database.RevisionEdit,
+					RevisionTxID:        tx.ID().String(),
+					RevisionActionIndex: j,
+					Message:             content.Message,
+					URL:                 content.URL,
+					Encoding:            encoding,
+					Timestamp:           blk.Tmstmp,
+					Height:              blk.Hght,
+				}
+				ref := content.Edit
+				if len(content.Delete) > 0 {
+					rev.Action, rev.Encoding, ref = database.RevisionDelete, "", content.Delete
+				}
+				if err := m.revise(revisions, ref, fromStr, rev, feeds); err != nil {
+					m.log.Info("Incoming revision was not honored", zap.String("from", fromStr), zap.String("action", rev.Action), zap.String("target", ref), zap.Error(err))
+					continue
+				}
+				m.log.Info("Appending new revision", zap.Stringer("TxID", tx.ID()), zap.Int("action", j), zap.String("revision", rev.Action), zap.String("target", ref))
+				revised++
+				continue
+			}
+
+			post := &FeedObject{
+				SubnetID:    m.subnetID.String(),
+				ChainID:     m.chainID.String(),
+				Address:     fromStr,
+				TxID:        tx.ID(),
+				Timestamp:   blk.Tmstmp,
+				Fee:         action.Value,
+				ActionIndex: j,
+				BlockID:     blkID,
+				Height:      blk.Hght,
+				Status:      database.StatusPending,
+				Encoding:    encoding,
+				Content:     content,
+			}
+			if len(content.ReplyTo) > 0 {
+				post.Parent, post.Root, err = m.resolveReply(content.ReplyTo, feeds, revisions)
+				if err != nil {
+					m.log.Info("Incoming reply does not answer a known post", zap.String("from", fromStr), zap.String("replyTo", content.ReplyTo), zap.Error(err))
+					continue
+				}
+				content.ReplyTo = post.Parent.String()
+			}
+			feed := toDatabaseFeed(post)
+			if _, ok := m.banned[fromStr]; ok {
+				m.log.Info("Hiding new feed from banned author", zap.Stringer("TxID", tx.ID()), zap.Int("action", j), zap.String("from", fromStr))
+				feed.Hidden = true
+			} else {
+				m.log.Info("Appending new feed", zap.Stringer("TxID", tx.ID()), zap.Int("action", j))
+				posts = append(posts, post)
+			}
+			feeds = append(feeds, *feed)
+		}
+	}
+
+	// Hidden posts, reactions and revisions were paid for, so they count
+	// towards the fee. The running epoch is saved with the block and only updated here
+	// once the block is indexed.
+	var (
+		next  = *m.fee
+		epoch *database.FeeEpoch
+	)
+	if paid := len(feeds) + len(reactions) + revised; paid > 0 {
+		for i := 0; i < paid; i++ {
+			next.RecordMessage()
+		}
+		epoch = feeEpoch(&next, 0)
+	}
+	if err := m.db.IndexBlock(&database.BlockObject{
+		ChainID:    m.chainID.String(),
+		Height:     blk.Hght,
+		BlockID:    blkID.String(),
+		ParentID:   blk.Prnt.String(),
+		Messages:   len(feeds) + len(reactions) + revised,
+		EpochStart: next.EpochStart().Unix(),
+	}, feeds, reactions, revisions.revisions, epoch); err != nil {
+		return fmt.Errorf("failed to index block %d: %w", blk.Hght, err)
+	}
+	*m.fee = next
+	m.subs.publish(posts)
+	return nil
 }
 
-func (m *Manager) Run(ctx context.Context) error {
-	m.log.Info("Manager run started")
-	m.t.SetTimeoutIn(time.Duration(m.config.TargetDurationPerEpoch) * time.Second)
-	go m.t.Dispatch()
-	defer m.t.Stop()
+// rollbackFrom rolls back the blocks of [chainID] from [height], see
+// database.Store.RollbackFrom, and stops counting their messages towards the
+// running fee epoch.
+func (m *Manager) rollbackFrom(chainID string, height uint64) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if err := m.db.RollbackFrom(chainID, height); err != nil {
+		return err
+	}
+	epoch, ok, err := m.db.GetCurrentFeeEpoch()
+	if err != nil {
+		return fmt.Errorf("failed to load fee epoch: %w", err)
+	}
+	if ok && epoch.EpochStart == m.fee.EpochStart().Unix() {
+		m.fee.Restore(m.fee.Fee(), m.fee.EpochStart(), epoch.Messages)
+	}
+	return nil
+}
 
-	var scli *rpc.WebSocketClient
-	currentRPCURL := m.config.NuklaiRPC
+// ingest processes [blk] unless it has already been indexed. If a different
+// block was indexed at the same height, or the block indexed below it is not
+// its parent, the conflicting blocks are rolled back first. Final blocks are
+// never rolled back.
+func (m *Manager) ingest(ctx context.Context, parser chain.Parser, blk *chain.StatefulBlock, results []*chain.Result) error {
+	blkID, err := blk.ID()
+	if err != nil {
+		return fmt.Errorf("failed to compute block ID: %w", err)
+	}
 
-	reconnect := func() error {
-		var err error
-		if scli != nil {
-			scli.Close()
+	_, chainID := m.currentChain()
+	stored, ok, err := m.db.GetBlock(chainID, blk.Hght)
+	if err != nil {
+		return fmt.Errorf("failed to load block %d: %w", blk.Hght, err)
+	}
+	if ok {
+		if stored.BlockID == blkID.String() {
+			m.log.Debug("Skipping already processed block", zap.Uint64("height", blk.Hght), zap.Stringer("blockID", blkID))
+			return nil
 		}
-		scli, err = rpc.NewWebSocketClient(m.config.NuklaiRPC, rpc.DefaultHandshakeTimeout, pubsub.MaxPendingMessages, pubsub.MaxReadMessageSize)
+		if stored.Final {
+			return fmt.Errorf("%w: height %d has %s, received %s", ErrFinalizedConflict, blk.Hght, stored.BlockID, blkID)
+		}
+		m.log.Warn("Different block accepted at indexed height, rolling back",
+			zap.Uint64("height", blk.Hght),
+			zap.String("oldBlockID", stored.BlockID),
+			zap.Stringer("newBlockID", blkID),
+		)
+		if err := m.rollbackFrom(chainID, blk.Hght); err != nil {
+			return fmt.Errorf("failed to roll back from %d: %w", blk.Hght, err)
+		}
+	}
+
+	if blk.Hght > 0 {
+		parent, ok, err := m.db.GetBlock(chainID, blk.Hght-1)
 		if err != nil {
-			m.log.Warn("Failed to connect to RPC", zap.String("uri", m.config.NuklaiRPC), zap.Error(err))
-			return fmt.Errorf("failed to connect to RPC: %w", err)
+			return fmt.Errorf("failed to load block %d: %w", blk.Hght-1, err)
 		}
-		if err = scli.RegisterBlocks(); err != nil {
-			m.log.Warn("Failed to register for blocks", zap.String("uri", m.config.NuklaiRPC), zap.Error(err))
-			return fmt.Errorf("failed to register for blocks: %w", err)
+		if ok && parent.BlockID != blk.Prnt.String() {
+			if parent.Final {
+				return fmt.Errorf("%w: block %s does not build on final block %s", ErrFinalizedConflict, blkID, parent.BlockID)
+			}
+			m.log.Warn("Block does not build on indexed parent, rolling back",
+				zap.Uint64("height", parent.Height),
+				zap.String("oldBlockID", parent.BlockID),
+				zap.Stringer("newBlockID", blk.Prnt),
+			)
+			if err := m.rollbackFrom(chainID, parent.Height); err != nil {
+				return fmt.Errorf("failed to roll back from %d: %w", parent.Height, err)
+			}
+			if err := m.backfill(ctx, parser, parent.Height, parent.Height); err != nil {
+				return err
+			}
 		}
-		m.log.Info("Connected to RPC and registered for blocks", zap.String("uri", m.config.NuklaiRPC))
-		return nil
 	}
 
-	if err := reconnect(); err != nil {
-		m.log.Error("Initial RPC connection failed", zap.Error(err))
+	if err := m.processBlock(blk, blkID, results); err != nil {
 		return err
 	}
+	if blk.Hght >= m.config.FinalityDepth {
+		if err := m.db.FinalizeBlocks(chainID, blk.Hght-m.config.FinalityDepth); err != nil {
+			return fmt.Errorf("failed to finalize blocks: %w", err)
+		}
+	}
+	return nil
+}
 
-	for ctx.Err() == nil {
-		if m.config.NuklaiRPC != currentRPCURL {
-			m.log.Info("Detected RPC URL change, reconnecting", zap.String("newURL", m.config.NuklaiRPC))
-			if err := reconnect(); err != nil {
-				m.log.Error("Reconnection failed", zap.Error(err))
-				continue
+// gapRetryInterval is how often recorded gaps are retried.
+const gapRetryInterval = time.Minute
+
+// backfill fetches and processes every block in [from, to] in order. Blocks
+// that cannot be fetched, or every block if no BlockFetcher is configured,
+// are recorded as a gap so that the live stream resumes and they are retried
+// by fillGaps.
+func (m *Manager) backfill(ctx context.Context, parser chain.Parser, from, to uint64) error {
+	if from > to {
+		return nil
+	}
+	fetcher := m.blockFetcher()
+	if fetcher == nil {
+		m.log.Warn("Recording missed blocks as a gap, no block fetcher is configured", zap.Uint64("from", from), zap.Uint64("to", to))
+		return m.skipBlocks(from, to)
+	}
+	m.log.Info("Backfilling missed blocks", zap.Uint64("from", from), zap.Uint64("to", to))
+	for height := from; height <= to; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		blk, results, err := fetcher.GetBlockByHeight(ctx, height, parser)
+		if errors.Is(err, ErrBlockUnavailable) {
+			m.log.Warn("Recording missed blocks as a gap, block is unavailable", zap.Uint64("from", height), zap.Uint64("to", to), zap.Error(err))
+			return m.skipBlocks(height, to)
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.ingest(ctx, parser, blk, results); err != nil {
+			return err
+		}
+	}
+	m.log.Info("Backfill completed", zap.Uint64("height", to))
+	return nil
+}
+
+// skipBlocks records the heights [from, to] of the current chain as a gap
+// and moves the block cursor past them.
+func (m *Manager) skipBlocks(from, to uint64) error {
+	_, chainID := m.currentChain()
+	if err := m.db.SkipBlocks(&database.BlockGap{ChainID: chainID, From: from, To: to}); err != nil {
+		return fmt.Errorf("failed to record gap %d-%d: %w", from, to, err)
+	}
+	return nil
+}
+
+// fillGaps fetches and processes the blocks in the gaps of the current chain,
+// lowest first, until a block is still unavailable.
+func (m *Manager) fillGaps(ctx context.Context, parser chain.Parser) error {
+	_, chainID := m.currentChain()
+	gaps, err := m.db.GetBlockGaps(chainID)
+	if err != nil {
+		return fmt.Errorf("failed to load block gaps: %w", err)
+	}
+	if len(gaps) == 0 {
+		return nil
+	}
+	fetcher := m.blockFetcher()
+	if fetcher == nil {
+		m.log.Warn("Blocks are missing from the index, no block fetcher is configured", zap.Int("gaps", len(gaps)), zap.Uint64("from", gaps[0].From))
+		return nil
+	}
+	for _, gap := range gaps {
+		for height := gap.From; height <= gap.To; height++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			blk, results, err := fetcher.GetBlockByHeight(ctx, height, parser)
+			if errors.Is(err, ErrBlockUnavailable) {
+				m.log.Info("Missed block is still unavailable", zap.Uint64("height", height), zap.Error(err))
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.ingest(ctx, parser, blk, results); err != nil {
+				return err
 			}
-			currentRPCURL = m.config.NuklaiRPC
 		}
+		m.log.Info("Filled gap of missed blocks", zap.Uint64("from", gap.From), zap.Uint64("to", gap.To))
+	}
+	return nil
+}
 
-		parser, err := m.ncli.Parser(ctx)
-		if err != nil {
-			m.log.Error("Failed to create parser", zap.Error(err))
+// catchUp processes all blocks accepted since the block cursor and retries
+// the recorded gaps. If no block has been processed yet, ingestion starts
+// from the live stream.
+func (m *Manager) catchUp(ctx context.Context, parser chain.Parser) error {
+	_, chainID := m.currentChain()
+	cursor, ok, err := m.db.GetBlockCursor(chainID)
+	if err != nil {
+		return fmt.Errorf("failed to load block cursor: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	_, height, _, err := m.cli.Accepted(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch last accepted block: %w", err)
+	}
+	if err := m.backfill(ctx, parser, cursor+1, height); err != nil {
+		return err
+	}
+	if err := m.fillGaps(ctx, parser); err != nil {
+		m.log.Warn("Unable to fill missed blocks", zap.Error(err))
+	}
+	return nil
+}
+
+// handleBlock processes a block received from the live stream, first filling
+// in any gap between the block cursor and [blk].
+func (m *Manager) handleBlock(ctx context.Context, parser chain.Parser, blk *chain.StatefulBlock, results []*chain.Result) error {
+	_, chainID := m.currentChain()
+	cursor, ok, err := m.db.GetBlockCursor(chainID)
+	if err != nil {
+		return fmt.Errorf("failed to load block cursor: %w", err)
+	}
+	if ok && blk.Hght > cursor+1 {
+		if err := m.backfill(ctx, parser, cursor+1, blk.Hght-1); err != nil {
 			return err
 		}
+	}
+	return m.ingest(ctx, parser, blk, results)
+}
 
-		blk, results, _, err := scli.ListenBlock(ctx, parser)
-		if err != nil {
-			m.log.Warn("Unable to listen for blocks", zap.Error(err))
-			time.Sleep(10 * time.Second)
-			continue
+// Run indexes the blocks streamed by the current endpoint until [ctx] is
+// done. Switches requested by UpdateNuklaiRPC are made between blocks.
+func (m *Manager) Run(ctx context.Context) error {
+	m.log.Info("Manager run started")
+	m.l.RLock()
+	m.t.SetTimeoutIn(m.fee.UntilEpochEnd())
+	m.l.RUnlock()
+	go m.t.Dispatch()
+	defer m.t.Stop()
+
+	// The stream is opened by the loop, so that a failed connection is
+	// retried and UpdateNuklaiRPC can switch to another endpoint meanwhile.
+	var (
+		s   *stream
+		err error
+	)
+	defer func() {
+		if s != nil {
+			s.close()
+		}
+	}()
+
+	// switchTo drains the stream of the old endpoint before replacing it and
+	// restarts the fee timer.
+	switchTo := func(req *rpcSwitch) {
+		if s != nil {
+			s.close()
+			s = nil
 		}
+		m.useEndpoint(req.endpoint)
+		m.l.RLock()
+		m.t.SetTimeoutIn(m.fee.UntilEpochEnd())
+		m.l.RUnlock()
+		m.log.Info("Switched Nuklai RPC", zap.String("uri", req.endpoint.url))
+		close(req.done)
+	}
+	// wait pauses before a retry, unless a switch is requested.
+	wait := func(d time.Duration) {
+		select {
+		case req := <-m.switches:
+			switchTo(req)
+		case <-time.After(d):
+		case <-ctx.Done():
+		}
+	}
 
-		for i, tx := range blk.Txs {
-			result := results[i]
-			if result.Success {
-				for _, act := range tx.Actions {
-					action, ok := act.(*actions.Transfer)
-
-					recipientAddr, err := m.config.RecipientAddress()
-					if err != nil {
-						m.log.Error("Failed to get recipient address", zap.Error(err))
-						return err
-					}
-					if !ok || action.To != recipientAddr {
-						continue
-					}
-
-					fromStr := codec.MustAddressBech32(nconsts.HRP, tx.Auth.Actor())
-					if !result.Success || action.Value < m.feeAmount {
-						m.log.Info("Incoming message failed or did not pay enough", zap.String("from", fromStr), zap.String("memo", string(action.Memo)), zap.Uint64("payment", action.Value), zap.Uint64("required", m.feeAmount))
-						continue
-					}
-
-					var content FeedContent
-					if err := json.Unmarshal(action.Memo, &content); err != nil || len(content.Message) == 0 {
-						m.log.Info("Incoming message could not be parsed or was empty", zap.String("from", fromStr), zap.String("memo", string(action.Memo)), zap.Uint64("payment", action.Value), zap.Error(err))
-						continue
-					}
-
-					m.appendFeed(&FeedObject{
-						SubnetID:  m.subnetID.String(),
-						ChainID:   m.chainID.String(),
-						Address:   fromStr,
-						TxID:      tx.ID(),
-						Timestamp: blk.Tmstmp,
-						Fee:       action.Value,
-						Content:   &content,
-					})
-				}
+	retryGaps := time.NewTicker(gapRetryInterval)
+	defer retryGaps.Stop()
+
+	// Blocks accepted while we were offline are processed before resuming the
+	// live stream.
+	needsCatchUp := true
+	for ctx.Err() == nil {
+		if s == nil {
+			if s, err = m.openStream(ctx); err != nil {
+				m.log.Error("RPC connection failed", zap.Error(err))
+				wait(10 * time.Second)
+				continue
+			}
+			needsCatchUp = true
+		}
+
+		if needsCatchUp {
+			if err := m.catchUp(ctx, s.parser); err != nil {
+				m.log.Warn("Unable to catch up with missed blocks", zap.Error(err))
+				wait(10 * time.Second)
+				continue
 			}
+			needsCatchUp = false
 		}
 
-		time.Sleep(1 * time.Second)
+		select {
+		case req := <-m.switches:
+			switchTo(req)
+		case msg, ok := <-s.blocks:
+			if !ok {
+				// The stream only ends without an error once [ctx] is done.
+				continue
+			}
+			if msg.err != nil {
+				m.log.Warn("Unable to listen for blocks", zap.Error(msg.err))
+				s.close()
+				s = nil
+				wait(10 * time.Second)
+				continue
+			}
+			if err := m.handleBlock(ctx, s.parser, msg.blk, msg.results); err != nil {
+				m.log.Warn("Unable to process block", zap.Uint64("height", msg.blk.Hght), zap.Error(err))
+				needsCatchUp = true
+			}
+		case <-retryGaps.C:
+			if err := m.fillGaps(ctx, s.parser); err != nil {
+				m.log.Warn("Unable to fill missed blocks", zap.Error(err))
+			}
+		case <-ctx.Done():
+		}
 	}
 
 	m.log.Info("Manager run completed", zap.Error(ctx.Err()))
@@ -280,46 +804,176 @@ func (m *Manager) GetFeedInfo(_ context.Context) (codec.Address, uint64, error)
 	if err != nil {
 		m.log.Error("Failed to get recipient address", zap.Error(err))
 	}
-	return addr, m.feeAmount, err
+	return addr, m.fee.Fee(), err
 }
 
-func (m *Manager) GetFeed(_ context.Context, subnetID, chainID string, limit int) ([]*FeedObject, error) {
-	return m.getLastFeeds(limit)
+// GetFeed returns a page of posts on [subnetID] and [chainID], starting at
+// [cursor] or at the newest post if it is empty. Empty IDs default to the
+// chain the feed is indexing; any other IDs are rejected with
+// ErrChainMismatch. [limit] is capped at the configured feed size.
+func (m *Manager) GetFeed(_ context.Context, subnetID, chainID, cursor string, limit int) (*FeedPage, error) {
+	currentSubnetID, currentChainID := m.currentChain()
+	if len(subnetID) == 0 {
+		subnetID = currentSubnetID
+	}
+	if len(chainID) == 0 {
+		chainID = currentChainID
+	}
+	if subnetID != currentSubnetID || chainID != currentChainID {
+		return nil, fmt.Errorf("%w: requested subnet %s chain %s, feed indexes subnet %s chain %s", ErrChainMismatch, subnetID, chainID, currentSubnetID, currentChainID)
+	}
+
+	pageCursor, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = m.pageSize(limit)
+	feeds, err := m.db.GetLastFeeds(subnetID, chainID, pageCursor, limit+1)
+	if err != nil {
+		m.log.Error("Failed to get last feeds from database", zap.Error(err))
+		return nil, err
+	}
+	return m.newFeedPage(feeds, cursor, pageCursor, limit), nil
 }
 
-func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string) error {
-	m.l.Lock()
-	defer m.l.Unlock()
+// GetFeedByTxID returns the post made by action [actionIndex] of [txID], or
+// the first post in the transaction if [actionIndex] is nil. ErrFeedNotFound
+// is returned if the transaction did not post to the chain the feed is
+// indexing.
+func (m *Manager) GetFeedByTxID(_ context.Context, txID string, actionIndex *int) (*FeedObject, error) {
+	feed, err := m.findFeed(txID, actionIndex)
+	if err != nil {
+		return nil, err
+	}
+	post, err := toFeedObject(feed)
+	if err != nil {
+		m.log.Warn("Malformed feed", zap.String("TxID", feed.TxID), zap.Int("action", feed.ActionIndex), zap.Error(err))
+		return nil, err
+	}
+	m.addReactions([]*FeedObject{post})
+	return post, nil
+}
+
+// findFeed returns the visible post made by action [actionIndex] of [txID],
+// or the first one if it is nil, on the chain the feed is indexing.
+func (m *Manager) findFeed(txID string, actionIndex *int) (*database.FeedObject, error) {
+	if _, err := ids.FromString(txID); err != nil {
+		return nil, fmt.Errorf("invalid txID %q: %w", txID, err)
+	}
+	feed, err := m.getFeedByTxID(txID, actionIndex)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, txID)
+		}
+		m.log.Error("Failed to get feed from database", zap.Error(err))
+		return nil, err
+	}
+	subnetID, chainID := m.currentChain()
+	if feed.SubnetID != subnetID || feed.ChainID != chainID {
+		return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, txID)
+	}
+	return feed, nil
+}
 
-	m.log.Info("Updating Nuklai RPC URL", zap.String("oldURL", m.config.NuklaiRPC), zap.String("newURL", newNuklaiRPCUrl))
+func (m *Manager) getFeedByTxID(txID string, actionIndex *int) (*database.FeedObject, error) {
+	if actionIndex != nil {
+		return m.db.GetFeed(txID, *actionIndex)
+	}
+	feeds, err := m.db.GetFeedsByTxID(txID)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) == 0 {
+		return nil, database.ErrNotFound
+	}
+	return &feeds[0], nil
+}
 
-	m.config.NuklaiRPC = newNuklaiRPCUrl
+// GetFeedByAddress returns a page of posts made by [address], paginated like
+// GetFeed. ErrFeedNotFound is returned if the address has never posted.
+func (m *Manager) GetFeedByAddress(_ context.Context, address, cursor string, limit int) (*FeedPage, error) {
+	if _, err := codec.ParseAddressBech32(nconsts.HRP, address); err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	pageCursor, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = m.pageSize(limit)
+	subnetID, chainID := m.currentChain()
+	feeds, err := m.db.GetFeedsByUser(subnetID, chainID, address, pageCursor, limit+1)
+	if err != nil {
+		m.log.Error("Failed to get feeds by address from database", zap.Error(err))
+		return nil, err
+	}
+	if len(feeds) == 0 && pageCursor == nil {
+		return nil, fmt.Errorf("%w: no posts by %s", ErrFeedNotFound, address)
+	}
+	return m.newFeedPage(feeds, cursor, pageCursor, limit), nil
+}
 
-	cli := rpc.NewJSONRPCClient(newNuklaiRPCUrl)
-	networkID, subnetID, chainID, err := cli.Network(ctx)
+// GetFeedSince returns up to [limit] posts published after [cursor] on the
+// chain the feed is indexing, oldest first. [cursor] may be any cursor
+// returned by the feed, including FeedObject.Cursor.
+func (m *Manager) GetFeedSince(_ context.Context, cursor string, limit int) ([]*FeedObject, error) {
+	pageCursor, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if pageCursor == nil {
+		return nil, ErrInvalidCursor
+	}
+	pageCursor.Newer = true
+	subnetID, chainID := m.currentChain()
+	feeds, err := m.db.GetLastFeeds(subnetID, chainID, pageCursor, m.pageSize(limit))
 	if err != nil {
-		m.log.Error("Failed to fetch network details", zap.Error(err))
-		return fmt.Errorf("failed to fetch network details: %w", err)
+		m.log.Error("Failed to get last feeds from database", zap.Error(err))
+		return nil, err
 	}
+	slices.Reverse(feeds)
+	return m.toFeedObjects(feeds), nil
+}
 
-	m.ncli = nrpc.NewJSONRPCClient(newNuklaiRPCUrl, networkID, chainID)
+// currentChain returns the subnet and chain the feed is indexing.
+func (m *Manager) currentChain() (string, string) {
+	m.l.RLock()
+	defer m.l.RUnlock()
 
-	m.subnetID = subnetID
-	m.chainID = chainID
-	m.epochStart = time.Now().Unix()
-	m.feeAmount = m.config.MinFee
-	m.t = timer.NewTimer(m.updateFee)
+	return m.subnetID.String(), m.chainID.String()
+}
 
-	m.log.Info("RPC client has been updated and manager reinitialized",
-		zap.String("new RPC URL", newNuklaiRPCUrl),
-		zap.Uint32("network ID", networkID),
-		zap.String("subnet ID", subnetID.String()),
-		zap.String("chain ID", chainID.String()),
-		zap.String("address", m.config.Recipient),
-		zap.String("fee", utils.FormatBalance(m.feeAmount, nconsts.Decimals)),
-	)
+// pageSize returns [limit] capped at the configured feed size, which is also
+// used when no limit is requested.
+func (m *Manager) pageSize(limit int) int {
+	if limit <= 0 || limit > m.config.FeedSize {
+		return m.config.FeedSize
+	}
+	return limit
+}
 
-	return nil
+// Subscribe returns a subscription that receives every post matching
+// [filter] as it is indexed. The caller must close the subscription.
+func (m *Manager) Subscribe(filter FeedFilter) *Subscription {
+	return m.subs.subscribe(filter)
+}
+
+// GetFeeHistory returns the [limit] most recent fee epochs, newest first.
+func (m *Manager) GetFeeHistory(_ context.Context, limit int) ([]*FeeEpoch, error) {
+	epochs, err := m.db.GetFeeHistory(m.pageSize(limit))
+	if err != nil {
+		m.log.Error("Failed to get fee history from database", zap.Error(err))
+		return nil, err
+	}
+	history := make([]*FeeEpoch, 0, len(epochs))
+	for _, epoch := range epochs {
+		history = append(history, &FeeEpoch{
+			EpochStart: epoch.EpochStart,
+			EpochEnd:   epoch.EpochEnd,
+			Fee:        epoch.Fee,
+			Messages:   epoch.Messages,
+		})
+	}
+	return history, nil
 }
 
 // Config returns the configuration of the manager
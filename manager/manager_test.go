@@ -0,0 +1,127 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	fconfig "github.com/nuklai/nuklai-feed/config"
+	"github.com/nuklai/nuklai-feed/database"
+	"github.com/nuklai/nuklaivm/actions"
+	"github.com/nuklai/nuklaivm/auth"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	_ "github.com/nuklai/nuklaivm/registry"
+)
+
+// newTestManager returns a Manager indexing into [db] without an endpoint.
+func newTestManager(t *testing.T, db database.Store) *Manager {
+	t.Helper()
+
+	config := &fconfig.Config{
+		FinalityDepth:          10,
+		Recipient:              codec.MustAddressBech32(nconsts.HRP, codec.CreateAddress(0, ids.GenerateTestID())),
+		FeedSize:               100,
+		MinFee:                 100,
+		FeeDelta:               10,
+		MessagesPerEpoch:       10,
+		TargetDurationPerEpoch: 60,
+	}
+	return &Manager{
+		log:      logging.NoLog{},
+		config:   config,
+		switches: make(chan *rpcSwitch),
+		fee:      NewFeeController(config, time.Now),
+		feed:     []*FeedObject{},
+		memos:    NewMemoDecoders(),
+		subs:     newBroadcaster(),
+		banned:   map[string]struct{}{},
+		db:       db,
+	}
+}
+
+// testBlocks returns a chain of empty blocks at heights 0 to [n].
+func testBlocks(t *testing.T, n uint64) []*chain.StatefulBlock {
+	t.Helper()
+
+	blocks := make([]*chain.StatefulBlock, 0, n+1)
+	var parent ids.ID
+	for height := uint64(0); height <= n; height++ {
+		blk := &chain.StatefulBlock{Prnt: parent, Tmstmp: int64(height) * 1000, Hght: height}
+		id, err := blk.ID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, blk)
+		parent = id
+	}
+	return blocks
+}
+
+func blockID(t *testing.T, blk *chain.StatefulBlock) ids.ID {
+	t.Helper()
+
+	id, err := blk.ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// testTransfer returns a transaction signed by a new key that transfers
+// [value] to the feed with [memo].
+func testTransfer(t *testing.T, m *Manager, value uint64, memo string) *chain.Transaction {
+	t.Helper()
+
+	return testTransferFrom(t, m, testKey(t), value, memo)
+}
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	priv, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// testAddress returns the address of [priv].
+func testAddress(priv ed25519.PrivateKey) string {
+	return codec.MustAddressBech32(nconsts.HRP, auth.NewED25519Address(priv.PublicKey()))
+}
+
+// testTransferFrom returns a transaction signed by [priv] that transfers
+// [value] to the feed with [memo].
+func testTransferFrom(t *testing.T, m *Manager, priv ed25519.PrivateKey, value uint64, memo string) *chain.Transaction {
+	t.Helper()
+
+	to, err := m.config.RecipientAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := chain.NewTx(&chain.Base{Timestamp: 1000, ChainID: ids.GenerateTestID(), MaxFee: 1}, []chain.Action{&actions.Transfer{To: to, Value: value, Memo: []byte(memo)}})
+	tx, err = tx.Sign(auth.NewED25519Factory(priv), nconsts.ActionRegistry, nconsts.AuthRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// processTxs indexes a block at [height] made of [txs], which all succeeded.
+func processTxs(t *testing.T, m *Manager, height uint64, txs ...*chain.Transaction) error {
+	t.Helper()
+
+	blk := &chain.StatefulBlock{Tmstmp: int64(height) * 1000, Hght: height, Txs: txs}
+	results := make([]*chain.Result, len(txs))
+	for i := range results {
+		results[i] = &chain.Result{Success: true}
+	}
+	return m.processBlock(blk, blockID(t, blk), results)
+}
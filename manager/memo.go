@@ -0,0 +1,205 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Memo prefixes of the built-in decoders. A memo starting with a registered
+// prefix byte is decoded by its decoder, without the prefix. Memos without a
+// prefix are decoded as JSON if they start with '{', as posts always were,
+// and as text otherwise.
+const (
+	MemoPrefixJSON   byte = 0x01
+	MemoPrefixText   byte = 0x02
+	MemoPrefixCBOR   byte = 0x03
+	MemoPrefixBinary byte = 0x04
+)
+
+var (
+	ErrUnknownMemo     = errors.New("memo has an unknown prefix")
+	ErrDuplicatePrefix = errors.New("memo prefix is already registered")
+	ErrInvalidPrefix   = errors.New("memo prefix must be a control character other than whitespace")
+	errInvalidUTF8     = errors.New("invalid UTF-8")
+)
+
+// MemoDecoder decodes the content of a post from a transfer memo.
+type MemoDecoder interface {
+	// Name identifies the decoder. It is recorded on every post it decodes.
+	Name() string
+	// Decode decodes [memo], without its prefix.
+	Decode(memo []byte) (*FeedContent, error)
+}
+
+// MemoDecoders selects the decoder of a memo by its prefix.
+type MemoDecoders struct {
+	decoders map[byte]MemoDecoder
+}
+
+// NewMemoDecoders returns a registry of the built-in decoders.
+func NewMemoDecoders() *MemoDecoders {
+	return &MemoDecoders{decoders: map[byte]MemoDecoder{
+		MemoPrefixJSON:   JSONMemoDecoder{},
+		MemoPrefixText:   TextMemoDecoder{},
+		MemoPrefixCBOR:   CBORMemoDecoder{},
+		MemoPrefixBinary: BinaryMemoDecoder{},
+	}}
+}
+
+// isPrefix reports whether [b] can be a memo prefix. Prefixes are control
+// characters other than whitespace, so they cannot be confused with text.
+func isPrefix(b byte) bool {
+	return b < 0x20 && b != '\t' && b != '\n' && b != '\r'
+}
+
+// Register decodes memos starting with [prefix] with [decoder].
+func (r *MemoDecoders) Register(prefix byte, decoder MemoDecoder) error {
+	if !isPrefix(prefix) {
+		return fmt.Errorf("%w: 0x%02x", ErrInvalidPrefix, prefix)
+	}
+	if _, ok := r.decoders[prefix]; ok {
+		return fmt.Errorf("%w: 0x%02x", ErrDuplicatePrefix, prefix)
+	}
+	r.decoders[prefix] = decoder
+	return nil
+}
+
+// Decode decodes [memo] and returns its content and the name of the decoder
+// used.
+func (r *MemoDecoders) Decode(memo []byte) (*FeedContent, string, error) {
+	if len(memo) == 0 {
+		return nil, "", errors.New("empty memo")
+	}
+	decoder, ok := r.decoders[memo[0]]
+	switch {
+	case ok:
+		memo = memo[1:]
+	case isPrefix(memo[0]):
+		return nil, "", fmt.Errorf("%w: 0x%02x", ErrUnknownMemo, memo[0])
+	case bytes.HasPrefix(bytes.TrimLeft(memo, " \t\r\n"), []byte("{")):
+		decoder = r.decoders[MemoPrefixJSON]
+	default:
+		decoder = r.decoders[MemoPrefixText]
+	}
+	content, err := decoder.Decode(memo)
+	if err != nil {
+		return nil, decoder.Name(), fmt.Errorf("%s memo: %w", decoder.Name(), err)
+	}
+	if err := validateContent(content); err != nil {
+		return nil, decoder.Name(), fmt.Errorf("%s memo: %w", decoder.Name(), err)
+	}
+	return content, decoder.Name(), nil
+}
+
+// validateContent checks that [content] is either a post, a reaction, an edit
+// or a deletion.
+func validateContent(content *FeedContent) error {
+	revision := len(content.Edit) > 0 || len(content.Delete) > 0
+	switch {
+	case len(content.ReactTo) > 0 && revision:
+		return errors.New("reactions cannot revise a post")
+	case len(content.ReactTo) > 0:
+		return validateReaction(content)
+	case len(content.Reaction) > 0:
+		return errors.New("reaction without reactTo")
+	case revision:
+		return validateRevision(content)
+	case len(content.Message) == 0:
+		return errors.New("empty message")
+	}
+	return nil
+}
+
+// JSONMemoDecoder decodes {"message": ..., "url": ...} objects.
+type JSONMemoDecoder struct{}
+
+func (JSONMemoDecoder) Name() string { return "json" }
+
+func (JSONMemoDecoder) Decode(memo []byte) (*FeedContent, error) {
+	var content FeedContent
+	if err := json.Unmarshal(memo, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// TextMemoDecoder decodes UTF-8 text as the message of a post.
+type TextMemoDecoder struct{}
+
+func (TextMemoDecoder) Name() string { return "text" }
+
+func (TextMemoDecoder) Decode(memo []byte) (*FeedContent, error) {
+	if !utf8.Valid(memo) {
+		return nil, errInvalidUTF8
+	}
+	return &FeedContent{Message: string(memo)}, nil
+}
+
+// CBORMemoDecoder decodes CBOR maps with the keys of the JSON encoding.
+type CBORMemoDecoder struct{}
+
+func (CBORMemoDecoder) Name() string { return "cbor" }
+
+func (CBORMemoDecoder) Decode(memo []byte) (*FeedContent, error) {
+	var content FeedContent
+	if err := cbor.Unmarshal(memo, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// BinaryMemoDecoder decodes the message, the URL and optionally the replyTo,
+// the reactTo and the reaction, the edit and the delete, packed as strings
+// with codec.Packer, which prefixes each with its length as a uint16.
+type BinaryMemoDecoder struct{}
+
+func (BinaryMemoDecoder) Name() string { return "binary" }
+
+func (BinaryMemoDecoder) Decode(memo []byte) (*FeedContent, error) {
+	p := codec.NewReader(memo, len(memo))
+	content := &FeedContent{
+		Message: p.UnpackString(false),
+		URL:     p.UnpackString(false),
+	}
+	if !p.Empty() {
+		content.ReplyTo = p.UnpackString(false)
+	}
+	if !p.Empty() {
+		content.ReactTo = p.UnpackString(false)
+		content.Reaction = p.UnpackString(false)
+	}
+	if !p.Empty() {
+		content.Edit = p.UnpackString(false)
+		content.Delete = p.UnpackString(false)
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	if !p.Empty() {
+		return nil, errors.New("trailing bytes")
+	}
+	if !utf8.ValidString(content.Message) || !utf8.ValidString(content.URL) || !utf8.ValidString(content.ReplyTo) ||
+		!utf8.ValidString(content.ReactTo) || !utf8.ValidString(content.Reaction) ||
+		!utf8.ValidString(content.Edit) || !utf8.ValidString(content.Delete) {
+		return nil, errInvalidUTF8
+	}
+	return content, nil
+}
+
+// RegisterMemoDecoder decodes the memos of new posts starting with [prefix]
+// with [decoder].
+func (m *Manager) RegisterMemoDecoder(prefix byte, decoder MemoDecoder) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	return m.memos.Register(prefix, decoder)
+}
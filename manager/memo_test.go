@@ -0,0 +1,157 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// binaryMemo packs [fields] like BinaryMemoDecoder expects them, after its
+// prefix.
+func binaryMemo(t testing.TB, fields ...string) []byte {
+	t.Helper()
+
+	p := codec.NewWriter(0, 1<<16)
+	for _, field := range fields {
+		p.PackString(field)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{MemoPrefixBinary}, p.Bytes()...)
+}
+
+func cborMemo(t testing.TB, fields map[string]string) []byte {
+	t.Helper()
+
+	raw, err := cbor.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{MemoPrefixCBOR}, raw...)
+}
+
+func TestMemoDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		memo     []byte
+		encoding string
+		content  *FeedContent // nil if the memo is rejected
+	}{
+		{"json", []byte("\x01{\"message\":\"hi\",\"url\":\"https://nukl.ai\"}"), "json", &FeedContent{Message: "hi", URL: "https://nukl.ai"}},
+		{"json reply", []byte("\x01{\"message\":\"hi\",\"replyTo\":\"tx\"}"), "json", &FeedContent{Message: "hi", ReplyTo: "tx"}},
+		{"json malformed", []byte("\x01{\"message\":"), "json", nil},
+		{"json not an object", []byte("\x01[1, 2]"), "json", nil},
+		{"json empty message", []byte("\x01{\"url\":\"https://nukl.ai\"}"), "json", nil},
+		{"text", []byte("\x02hello world"), "text", &FeedContent{Message: "hello world"}},
+		{"text invalid UTF-8", []byte("\x02\xff\xfe"), "text", nil},
+		{"text empty", []byte{MemoPrefixText}, "text", nil},
+		{"cbor", cborMemo(t, map[string]string{"message": "hi", "url": "https://nukl.ai"}), "cbor", &FeedContent{Message: "hi", URL: "https://nukl.ai"}},
+		{"cbor reaction", cborMemo(t, map[string]string{"reactTo": "tx", "reaction": "+1"}), "cbor", &FeedContent{ReactTo: "tx", Reaction: "+1"}},
+		{"cbor malformed", []byte{MemoPrefixCBOR, 0xbf, 0x67}, "cbor", nil},
+		{"cbor not a map", []byte{MemoPrefixCBOR, 0x01}, "cbor", nil},
+		{"binary", binaryMemo(t, "hi", "https://nukl.ai"), "binary", &FeedContent{Message: "hi", URL: "https://nukl.ai"}},
+		{"binary reply", binaryMemo(t, "hi", "", "tx"), "binary", &FeedContent{Message: "hi", ReplyTo: "tx"}},
+		{"binary reaction", binaryMemo(t, "", "", "", "tx", "+1"), "binary", &FeedContent{ReactTo: "tx", Reaction: "+1"}},
+		{"binary edit", binaryMemo(t, "fixed", "", "", "", "", "tx", ""), "binary", &FeedContent{Message: "fixed", Edit: "tx"}},
+		{"binary missing reaction", binaryMemo(t, "", "", "", "tx"), "binary", nil},
+		{"binary truncated", binaryMemo(t, "hello")[:4], "binary", nil},
+		{"binary oversized length", []byte{MemoPrefixBinary, 0xff, 0xff, 'h', 'i'}, "binary", nil},
+		{"binary trailing bytes", append(binaryMemo(t, "hi", "", "", "", "", "", ""), 0x00), "binary", nil},
+		{"binary invalid UTF-8", binaryMemo(t, "\xff", ""), "binary", nil},
+		{"unprefixed json", []byte(` {"message":"hi"}`), "json", &FeedContent{Message: "hi"}},
+		{"unprefixed text", []byte("hello"), "text", &FeedContent{Message: "hello"}},
+		{"unknown prefix", []byte("\x05hello"), "", nil},
+		{"prefix only", []byte{MemoPrefixJSON}, "json", nil},
+		{"empty", nil, "", nil},
+	}
+	r := NewMemoDecoders()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, encoding, err := r.Decode(tt.memo)
+			if encoding != tt.encoding {
+				t.Errorf("encoding = %q, want %q", encoding, tt.encoding)
+			}
+			if tt.content == nil {
+				if err == nil {
+					t.Fatalf("decoded %+v, want an error", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *content != *tt.content {
+				t.Fatalf("content = %+v, want %+v", content, tt.content)
+			}
+		})
+	}
+	if _, _, err := r.Decode([]byte("\x05hello")); !errors.Is(err, ErrUnknownMemo) {
+		t.Fatalf("unknown prefix: err = %v, want %v", err, ErrUnknownMemo)
+	}
+}
+
+func TestMemoRegister(t *testing.T) {
+	r := NewMemoDecoders()
+	if err := r.Register(MemoPrefixText, TextMemoDecoder{}); !errors.Is(err, ErrDuplicatePrefix) {
+		t.Fatalf("duplicate prefix: err = %v, want %v", err, ErrDuplicatePrefix)
+	}
+	for _, prefix := range []byte{'\n', '{', 'a', 0x20} {
+		if err := r.Register(prefix, TextMemoDecoder{}); !errors.Is(err, ErrInvalidPrefix) {
+			t.Fatalf("prefix 0x%02x: err = %v, want %v", prefix, err, ErrInvalidPrefix)
+		}
+	}
+	if err := r.Register(0x05, TextMemoDecoder{}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _, err := r.Decode([]byte("\x05hello")); err != nil || content.Message != "hello" {
+		t.Fatalf("registered prefix decoded %+v (%v), want hello", content, err)
+	}
+}
+
+func FuzzMemoDecode(f *testing.F) {
+	f.Add([]byte("\x01{\"message\":\"hi\"}"))
+	f.Add([]byte("\x02hi"))
+	f.Add(cborMemo(f, map[string]string{"message": "hi"}))
+	f.Add(binaryMemo(f, "hi", "", "", "", "", "", ""))
+	f.Add([]byte("hi"))
+	r := NewMemoDecoders()
+	f.Fuzz(func(t *testing.T, memo []byte) {
+		content, _, err := r.Decode(memo)
+		if err == nil && content == nil {
+			t.Fatal("no content and no error")
+		}
+	})
+}
+
+func TestMalformedMemosSkipped(t *testing.T) {
+	db := database.NewMemoryDB()
+	m := newTestManager(t, db)
+
+	valid := testTransfer(t, m, 100, "\x02valid")
+	txs := []*chain.Transaction{
+		testTransfer(t, m, 100, "\x01{\"message\":"),
+		testTransfer(t, m, 100, "\x02\xff"),
+		testTransfer(t, m, 100, "\x03\xbf"),
+		testTransfer(t, m, 100, "\x04\xff\xff"),
+		testTransfer(t, m, 100, "\x05unknown"),
+		valid,
+	}
+	if err := processTxs(t, m, 1, txs...); err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := db.GetAllFeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].TxID != valid.ID().String() || feeds[0].Message != "valid" {
+		t.Fatalf("indexed %+v, want only the valid post", feeds)
+	}
+}
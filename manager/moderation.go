@@ -0,0 +1,149 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-feed/database"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+// ErrMissingReason is returned when a moderation action does not say who
+// took it and why.
+var ErrMissingReason = errors.New("moderator and reason are required")
+
+// ModerationEntry records a moderation action. Moderator is the verified
+// identity of the admin who took it, and ModeratorName the display name they
+// supplied, if any.
+type ModerationEntry struct {
+	ID            int64  `json:"id"`
+	Action        string `json:"action"`
+	Target        string `json:"target"`
+	Moderator     string `json:"moderator"`
+	ModeratorName string `json:"moderatorName,omitempty"`
+	Reason        string `json:"reason"`
+	CreatedAt     int64  `json:"createdAt"`
+}
+
+// loadBannedAuthors loads the banned authors from the database.
+func (m *Manager) loadBannedAuthors() error {
+	addresses, err := m.db.GetBannedAuthors()
+	if err != nil {
+		m.log.Error("Failed to load banned authors", zap.Error(err))
+		return err
+	}
+	m.banned = make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		m.banned[address] = struct{}{}
+	}
+	return nil
+}
+
+func newModerationEntry(action, target, moderator, moderatorName, reason string) (*database.ModerationEntry, error) {
+	if len(moderator) == 0 || len(reason) == 0 {
+		return nil, ErrMissingReason
+	}
+	return &database.ModerationEntry{
+		Action:        action,
+		Target:        target,
+		Moderator:     moderator,
+		ModeratorName: moderatorName,
+		Reason:        reason,
+		CreatedAt:     time.Now().Unix(),
+	}, nil
+}
+
+// HidePost hides, or unhides if [hidden] is false, the posts made in [txID],
+// or only the post made by action [actionIndex] if it is set. The action is
+// recorded in the moderation log under the verified identity [moderator] and
+// the optional display name [moderatorName].
+func (m *Manager) HidePost(_ context.Context, txID string, actionIndex *int, hidden bool, moderator, moderatorName, reason string) error {
+	if _, err := ids.FromString(txID); err != nil {
+		return fmt.Errorf("invalid txID %q: %w", txID, err)
+	}
+	action, target := database.ModerationHide, txID
+	if !hidden {
+		action = database.ModerationUnhide
+	}
+	if actionIndex != nil {
+		target = fmt.Sprintf("%s/%d", txID, *actionIndex)
+	}
+	entry, err := newModerationEntry(action, target, moderator, moderatorName, reason)
+	if err != nil {
+		return err
+	}
+	if err := m.db.SetFeedsHidden(txID, actionIndex, hidden, entry); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrFeedNotFound, target)
+		}
+		m.log.Error("Failed to hide feed", zap.Error(err))
+		return err
+	}
+	m.log.Info("Post moderated", zap.String("action", action), zap.String("target", target), zap.String("moderator", moderator))
+	return nil
+}
+
+// BanAuthor bans, or unbans if [banned] is false, [address]. Posts by banned
+// authors are still indexed and paid for, but hidden. Posts made before the
+// ban are not affected. The action is recorded in the moderation log like in
+// HidePost.
+func (m *Manager) BanAuthor(_ context.Context, address string, banned bool, moderator, moderatorName, reason string) error {
+	if _, err := codec.ParseAddressBech32(nconsts.HRP, address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	action := database.ModerationBan
+	if !banned {
+		action = database.ModerationUnban
+	}
+	entry, err := newModerationEntry(action, address, moderator, moderatorName, reason)
+	if err != nil {
+		return err
+	}
+
+	// Hold the lock so the ban applies from a block boundary.
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if err := m.db.SetAuthorBanned(address, banned, entry); err != nil {
+		m.log.Error("Failed to ban author", zap.Error(err))
+		return err
+	}
+	if banned {
+		m.banned[address] = struct{}{}
+	} else {
+		delete(m.banned, address)
+	}
+	m.log.Info("Author moderated", zap.String("action", action), zap.String("address", address), zap.String("moderator", moderator))
+	return nil
+}
+
+// GetModerationLog returns the [limit] most recent moderation actions,
+// newest first.
+func (m *Manager) GetModerationLog(_ context.Context, limit int) ([]*ModerationEntry, error) {
+	entries, err := m.db.GetModerationLog(m.pageSize(limit))
+	if err != nil {
+		m.log.Error("Failed to get moderation log from database", zap.Error(err))
+		return nil, err
+	}
+	log := make([]*ModerationEntry, 0, len(entries))
+	for _, entry := range entries {
+		log = append(log, &ModerationEntry{
+			ID:            entry.ID,
+			Action:        entry.Action,
+			Target:        entry.Target,
+			Moderator:     entry.Moderator,
+			ModeratorName: entry.ModeratorName,
+			Reason:        entry.Reason,
+			CreatedAt:     entry.CreatedAt,
+		})
+	}
+	return log, nil
+}
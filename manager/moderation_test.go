@@ -0,0 +1,84 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// checkHidden checks that the posts of [txs] were indexed, hidden if
+// [hidden] is set.
+func checkHidden(t *testing.T, db database.Store, hidden bool, txs ...*chain.Transaction) {
+	t.Helper()
+
+	feeds, err := db.GetAllFeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := make(map[string]database.FeedObject, len(feeds))
+	for _, feed := range feeds {
+		indexed[feed.TxID] = feed
+	}
+	for _, tx := range txs {
+		feed, ok := indexed[tx.ID().String()]
+		if !ok {
+			t.Fatalf("%s was not indexed", tx.ID())
+		}
+		if feed.Hidden != hidden {
+			t.Fatalf("%s is hidden: %t, want %t", tx.ID(), feed.Hidden, hidden)
+		}
+	}
+}
+
+func TestBanAuthor(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+			author := testKey(t)
+			address := testAddress(author)
+
+			before := testTransferFrom(t, m, author, 100, "before the ban")
+			if err := processTxs(t, m, 1, before); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.BanAuthor(context.Background(), address, true, "admin", "", "spam"); err != nil {
+				t.Fatal(err)
+			}
+			banned := testTransferFrom(t, m, author, 100, "while banned")
+			other := testTransfer(t, m, 100, "other author")
+			if err := processTxs(t, m, 2, banned, other); err != nil {
+				t.Fatal(err)
+			}
+			// Posts made while banned are indexed and paid for, but hidden.
+			checkHidden(t, db, false, before, other)
+			checkHidden(t, db, true, banned)
+			if messages := m.fee.EpochMessages(); messages != 3 {
+				t.Fatalf("epoch has %d messages, want 3", messages)
+			}
+
+			// Unbanning only affects the posts made afterwards.
+			if err := m.BanAuthor(context.Background(), address, false, "admin", "", "appeal"); err != nil {
+				t.Fatal(err)
+			}
+			after := testTransferFrom(t, m, author, 100, "after the unban")
+			if err := processTxs(t, m, 3, after); err != nil {
+				t.Fatal(err)
+			}
+			checkHidden(t, db, false, before, after)
+			checkHidden(t, db, true, banned)
+
+			entries, err := m.GetModerationLog(context.Background(), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || entries[0].Action != database.ModerationUnban || entries[1].Action != database.ModerationBan || entries[1].Target != address {
+				t.Fatalf("moderation log = %+v, want the ban then the unban of %s", entries, address)
+			}
+		})
+	}
+}
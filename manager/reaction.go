@@ -0,0 +1,116 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-feed/database"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+// MaxReactionLen is the maximum length of a reaction in bytes, enough for a
+// short word or a few emoji.
+const MaxReactionLen = 32
+
+// ErrInvalidReaction is returned when a reaction is malformed or the post it
+// reacts to cannot be found, is hidden or was deleted.
+var ErrInvalidReaction = errors.New("invalid reaction")
+
+// AuthorStats counts the visible posts of an author and the reactions to
+// them, and sums the tips they received.
+type AuthorStats struct {
+	Address   string `json:"address"`
+	Posts     int    `json:"posts"`
+	Reactions int    `json:"reactions"`
+	Tips      uint64 `json:"tips"`
+}
+
+// validateReaction checks the content of a reaction memo.
+func validateReaction(content *FeedContent) error {
+	switch {
+	case len(content.Message) > 0 || len(content.URL) > 0 || len(content.ReplyTo) > 0:
+		return fmt.Errorf("%w: reactions cannot carry a post", ErrInvalidReaction)
+	case len(content.Reaction) == 0:
+		return fmt.Errorf("%w: empty reaction", ErrInvalidReaction)
+	case len(content.Reaction) > MaxReactionLen:
+		return fmt.Errorf("%w: reaction longer than %d bytes", ErrInvalidReaction, MaxReactionLen)
+	case !utf8.ValidString(content.Reaction):
+		return errInvalidUTF8
+	}
+	return nil
+}
+
+// resolveReaction finds the post a reaction to [reactTo] targets, see
+// findLivePost. The caller must hold the lock.
+func (m *Manager) resolveReaction(reactTo string, pending []database.FeedObject, b *blockRevisions) (*database.FeedObject, error) {
+	target, err := m.findLivePost(reactTo, pending, b)
+	if errors.Is(err, ErrFeedNotFound) || errors.Is(err, errInvalidPostRef) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReaction, err)
+	}
+	return target, err
+}
+
+// addReactions sets the reaction counts and tips of [posts]. Failures are
+// logged and leave the posts without reactions, so that they do not fail a
+// whole page.
+func (m *Manager) addReactions(posts []*FeedObject) {
+	if len(posts) == 0 {
+		return
+	}
+	txIDs := make([]string, 0, len(posts))
+	byRef := make(map[PostRef]*FeedObject, len(posts))
+	for _, post := range posts {
+		ref := PostRef{post.TxID, post.ActionIndex}
+		if _, ok := byRef[ref]; !ok {
+			txIDs = append(txIDs, post.TxID.String())
+		}
+		byRef[ref] = post
+	}
+	totals, err := m.db.GetReactionTotals(txIDs)
+	if err != nil {
+		m.log.Error("Failed to get reaction totals from database", zap.Error(err))
+		return
+	}
+	for _, total := range totals {
+		ref, err := newPostRef(total.TargetTxID, total.TargetActionIndex)
+		if err != nil {
+			continue
+		}
+		post, ok := byRef[*ref]
+		if !ok {
+			continue
+		}
+		if post.Reactions == nil {
+			post.Reactions = map[string]int{}
+		}
+		post.Reactions[total.Reaction] += total.Count
+		post.Tips = database.AddAmounts(post.Tips, total.Tips)
+	}
+}
+
+// GetAuthorStats returns the posts of [address] on the chain the feed is
+// indexing and the reactions and tips they received.
+func (m *Manager) GetAuthorStats(_ context.Context, address string) (*AuthorStats, error) {
+	if _, err := codec.ParseAddressBech32(nconsts.HRP, address); err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	subnetID, chainID := m.currentChain()
+	stats, err := m.db.GetAuthorStats(subnetID, chainID, address)
+	if err != nil {
+		m.log.Error("Failed to get author stats from database", zap.Error(err))
+		return nil, err
+	}
+	return &AuthorStats{
+		Address:   address,
+		Posts:     stats.Posts,
+		Reactions: stats.Reactions,
+		Tips:      stats.Tips,
+	}, nil
+}
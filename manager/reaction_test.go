@@ -0,0 +1,78 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/chain"
+)
+
+func reactMemo(reactTo, reaction string) string {
+	return fmt.Sprintf(`{"reactTo":%q,"reaction":%q}`, reactTo, reaction)
+}
+
+// checkReactions checks the reactions counted on the post of [tx].
+func checkReactions(t *testing.T, m *Manager, tx *chain.Transaction, want map[string]int) {
+	t.Helper()
+
+	post, err := m.GetFeedByTxID(context.Background(), tx.ID().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(post.Reactions) != len(want) {
+		t.Fatalf("post has reactions %v, want %v", post.Reactions, want)
+	}
+	for reaction, count := range want {
+		if post.Reactions[reaction] != count {
+			t.Fatalf("post has reactions %v, want %v", post.Reactions, want)
+		}
+	}
+}
+
+func TestReactionToUnavailablePostRejected(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+			author := testKey(t)
+
+			post := testTransfer(t, m, 100, "post")
+			hidden := testTransfer(t, m, 100, "hidden")
+			deleted := testTransferFrom(t, m, author, 100, "deleted")
+			if err := processTxs(t, m, 1, post, hidden, deleted); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.HidePost(context.Background(), hidden.ID().String(), nil, true, "admin", "", "spam"); err != nil {
+				t.Fatal(err)
+			}
+
+			reactions := []*chain.Transaction{
+				testTransfer(t, m, 100, reactMemo(post.ID().String(), "like")),
+				testTransfer(t, m, 100, reactMemo(post.ID().String()+"/1", "like")),
+				testTransfer(t, m, 100, reactMemo(testTransfer(t, m, 100, "never indexed").ID().String(), "like")),
+				testTransfer(t, m, 100, reactMemo(hidden.ID().String(), "like")),
+				testTransferFrom(t, m, author, 100, deleteMemo(deleted.ID().String())),
+				testTransfer(t, m, 100, reactMemo(deleted.ID().String(), "like")),
+			}
+			if err := processTxs(t, m, 2, reactions...); err != nil {
+				t.Fatal(err)
+			}
+			checkReactions(t, m, post, map[string]int{"like": 1})
+
+			// Reactions made while the post was hidden are not counted once it
+			// is visible again.
+			if err := m.HidePost(context.Background(), hidden.ID().String(), nil, false, "admin", "", "appeal"); err != nil {
+				t.Fatal(err)
+			}
+			checkReactions(t, m, hidden, nil)
+			checkReactions(t, m, deleted, nil)
+			if err := processTxs(t, m, 3, testTransfer(t, m, 100, reactMemo(hidden.ID().String(), "like"))); err != nil {
+				t.Fatal(err)
+			}
+			checkReactions(t, m, hidden, map[string]int{"like": 1})
+		})
+	}
+}
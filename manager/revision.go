@@ -0,0 +1,92 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// ErrInvalidRevision is returned when an edit or deletion is malformed, its
+// post cannot be found or it was not sent by the author of the post.
+var ErrInvalidRevision = errors.New("invalid revision")
+
+// validateRevision checks the content of an edit or deletion memo.
+func validateRevision(content *FeedContent) error {
+	switch {
+	case len(content.Edit) > 0 && len(content.Delete) > 0:
+		return fmt.Errorf("%w: memo both edits and deletes", ErrInvalidRevision)
+	case len(content.ReplyTo) > 0:
+		return fmt.Errorf("%w: revisions cannot change replyTo", ErrInvalidRevision)
+	case len(content.Edit) > 0 && len(content.Message) == 0:
+		return fmt.Errorf("%w: empty message", ErrInvalidRevision)
+	case len(content.Delete) > 0 && (len(content.Message) > 0 || len(content.URL) > 0):
+		return fmt.Errorf("%w: deletions cannot carry a post", ErrInvalidRevision)
+	}
+	return nil
+}
+
+// blockRevisions collects the edits and deletions made by a block, and the
+// latest version of the posts they revise.
+type blockRevisions struct {
+	revisions []database.RevisionObject
+	latest    map[PostRef]*database.FeedObject
+}
+
+func newBlockRevisions() *blockRevisions {
+	return &blockRevisions{latest: map[PostRef]*database.FeedObject{}}
+}
+
+// revise applies [rev], an edit or deletion sent by [author], to the post
+// [ref] refers to, see findPost. Only the author of a post can revise it, and
+// deleted posts cannot be revised. The original post is recorded as revision
+// 0 the first time it is revised. The caller must hold the lock.
+func (m *Manager) revise(b *blockRevisions, ref, author string, rev database.RevisionObject, pending []database.FeedObject) error {
+	target, err := m.findPost(ref, pending)
+	if errors.Is(err, ErrFeedNotFound) || errors.Is(err, errInvalidPostRef) {
+		return fmt.Errorf("%w: %w", ErrInvalidRevision, err)
+	}
+	if err != nil {
+		return err
+	}
+	key, err := newPostRef(target.TxID, target.ActionIndex)
+	if err != nil {
+		return err
+	}
+	if latest, ok := b.latest[*key]; ok {
+		target = latest
+	}
+	if target.Address != author {
+		return fmt.Errorf("%w: %s was not posted by %s", ErrInvalidRevision, key, author)
+	}
+	if target.Deleted {
+		return fmt.Errorf("%w: %s was deleted", ErrInvalidRevision, key)
+	}
+
+	if target.Revision == 0 {
+		b.revisions = append(b.revisions, database.RevisionObject{
+			ChainID:             m.chainID.String(),
+			TxID:                target.TxID,
+			ActionIndex:         target.ActionIndex,
+			Action:              database.RevisionPost,
+			RevisionTxID:        target.TxID,
+			RevisionActionIndex: target.ActionIndex,
+			Message:             target.Message,
+			URL:                 target.URL,
+			Encoding:            target.Encoding,
+			Timestamp:           target.Timestamp,
+			Height:              target.Height,
+		})
+	}
+	rev.TxID, rev.ActionIndex, rev.Revision = target.TxID, target.ActionIndex, target.Revision+1
+	b.revisions = append(b.revisions, rev)
+
+	revised := *target
+	revised.Message, revised.URL, revised.Encoding = rev.Message, rev.URL, rev.Encoding
+	revised.Revision, revised.Deleted = rev.Revision, rev.Action == database.RevisionDelete
+	b.latest[*key] = &revised
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuklai/nuklai-feed/database"
+)
+
+// testStores returns a fresh memory and SQLite store.
+func testStores(t *testing.T) map[string]database.Store {
+	t.Helper()
+
+	sqlite, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "feed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sqlite.Close)
+	return map[string]database.Store{
+		"memory": database.NewMemoryDB(),
+		"sqlite": sqlite,
+	}
+}
+
+func editMemo(txID, message string) string {
+	return fmt.Sprintf(`{"edit":%q,"message":%q}`, txID, message)
+}
+
+// checkPost checks the message of the post made by [txID] and whether it is
+// reported as edited.
+func checkPost(t *testing.T, m *Manager, txID, message string, edited bool) {
+	t.Helper()
+
+	post, err := m.GetFeedByTxID(context.Background(), txID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Content.Message != message || post.Edited != edited {
+		t.Fatalf("post has message %q, edited %t, want %q, %t", post.Content.Message, post.Edited, message, edited)
+	}
+}
+
+func TestRevisionByOtherAddressIgnored(t *testing.T) {
+	m := newTestManager(t, database.NewMemoryDB())
+	author := testKey(t)
+
+	post := testTransferFrom(t, m, author, 100, "original")
+	if err := processTxs(t, m, 1, post); err != nil {
+		t.Fatal(err)
+	}
+	edit := testTransfer(t, m, 100, editMemo(post.ID().String(), "hijacked"))
+	deletion := testTransfer(t, m, 100, fmt.Sprintf(`{"delete":%q}`, post.ID()))
+	if err := processTxs(t, m, 2, edit, deletion); err != nil {
+		t.Fatal(err)
+	}
+	checkPost(t, m, post.ID().String(), "original", false)
+}
+
+func TestEditRollback(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+			author := testKey(t)
+			post := testTransferFrom(t, m, author, 100, "original")
+			txID := post.ID().String()
+			if err := processTxs(t, m, 1, post); err != nil {
+				t.Fatal(err)
+			}
+			if err := processTxs(t, m, 2, testTransferFrom(t, m, author, 100, editMemo(txID, "first edit"))); err != nil {
+				t.Fatal(err)
+			}
+			checkPost(t, m, txID, "first edit", true)
+			if err := processTxs(t, m, 3, testTransferFrom(t, m, author, 100, editMemo(txID, "second edit"))); err != nil {
+				t.Fatal(err)
+			}
+			checkPost(t, m, txID, "second edit", true)
+
+			// Rolling back the block of an edit restores the previous version,
+			// down to the original post kept as revision 0.
+			if err := db.RollbackFrom(m.chainID.String(), 3); err != nil {
+				t.Fatal(err)
+			}
+			checkPost(t, m, txID, "first edit", true)
+			if err := db.RollbackFrom(m.chainID.String(), 2); err != nil {
+				t.Fatal(err)
+			}
+			checkPost(t, m, txID, "original", false)
+
+			// The post can be edited again once the edits are rolled back.
+			if err := processTxs(t, m, 2, testTransferFrom(t, m, author, 100, editMemo(txID, "new edit"))); err != nil {
+				t.Fatal(err)
+			}
+			checkPost(t, m, txID, "new edit", true)
+		})
+	}
+}
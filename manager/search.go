@@ -0,0 +1,55 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-feed/database"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+// FeedQuery selects the posts returned by SearchFeed. Empty fields match
+// every post.
+type FeedQuery struct {
+	Text    string
+	Address string
+	From    int64 // unix milliseconds, inclusive
+	To      int64 // unix milliseconds, exclusive
+}
+
+// SearchFeed returns a page of posts on the chain the feed is indexing that
+// match [query], paginated like GetFeed.
+func (m *Manager) SearchFeed(_ context.Context, query FeedQuery, cursor string, limit int) (*FeedPage, error) {
+	if len(query.Address) > 0 {
+		if _, err := codec.ParseAddressBech32(nconsts.HRP, query.Address); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", query.Address, err)
+		}
+	}
+	if query.From < 0 || query.To < 0 || (query.To > 0 && query.To <= query.From) {
+		return nil, fmt.Errorf("invalid time range [%d, %d)", query.From, query.To)
+	}
+	pageCursor, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = m.pageSize(limit)
+	subnetID, chainID := m.currentChain()
+	feeds, err := m.db.SearchFeeds(&database.SearchQuery{
+		SubnetID: subnetID,
+		ChainID:  chainID,
+		Text:     query.Text,
+		Address:  query.Address,
+		From:     query.From,
+		To:       query.To,
+	}, pageCursor, limit+1)
+	if err != nil {
+		m.log.Error("Failed to search feeds in database", zap.Error(err))
+		return nil, err
+	}
+	return m.newFeedPage(feeds, cursor, pageCursor, limit), nil
+}
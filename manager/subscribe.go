@@ -0,0 +1,101 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import "sync"
+
+// subscriptionBuffer is how many posts a subscriber may fall behind before it
+// is dropped.
+const subscriptionBuffer = 64
+
+// FeedFilter selects the posts delivered to a subscription. Empty fields
+// match every post.
+type FeedFilter struct {
+	Address string `json:"address,omitempty"`
+	MinFee  uint64 `json:"minFee,omitempty"`
+	ChainID string `json:"chainID,omitempty"`
+}
+
+// Match reports whether [feed] passes the filter.
+func (f *FeedFilter) Match(feed *FeedObject) bool {
+	if len(f.Address) > 0 && feed.Address != f.Address {
+		return false
+	}
+	if feed.Fee < f.MinFee {
+		return false
+	}
+	if len(f.ChainID) > 0 && feed.ChainID != f.ChainID {
+		return false
+	}
+	return true
+}
+
+// Subscription receives posts as they are indexed. C is closed when the
+// subscription is closed, or when the subscriber falls too far behind.
+type Subscription struct {
+	C <-chan *FeedObject
+
+	c      chan *FeedObject
+	filter FeedFilter
+	b      *broadcaster
+}
+
+// Close stops delivery to the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.b.remove(s)
+}
+
+// broadcaster fans new posts out to subscriptions. Sends never block, so a
+// slow subscriber cannot stall ingestion.
+type broadcaster struct {
+	l    sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: map[*Subscription]struct{}{}}
+}
+
+func (b *broadcaster) subscribe(filter FeedFilter) *Subscription {
+	c := make(chan *FeedObject, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, filter: filter, b: b}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *broadcaster) remove(s *Subscription) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+func (b *broadcaster) publish(feeds []*FeedObject) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	for s := range b.subs {
+		for _, feed := range feeds {
+			if !s.filter.Match(feed) {
+				continue
+			}
+			select {
+			case s.c <- feed:
+				continue
+			default:
+			}
+			// The subscriber fell behind, drop it.
+			delete(b.subs, s)
+			close(s.c)
+			break
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/nuklai/nuklai-feed/database"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidReply is returned when the post a reply answers cannot be
+	// found, is hidden or was deleted.
+	ErrInvalidReply   = errors.New("invalid reply")
+	errInvalidPostRef = errors.New("invalid post reference")
+)
+
+// PostRef identifies a post by its transaction and action index.
+type PostRef struct {
+	TxID        ids.ID `json:"txID"`
+	ActionIndex int    `json:"actionIndex"`
+}
+
+func (r *PostRef) String() string {
+	return fmt.Sprintf("%s/%d", r.TxID, r.ActionIndex)
+}
+
+// newPostRef returns the reference stored in [txID] and [actionIndex], or nil
+// if [txID] is empty.
+func newPostRef(txID string, actionIndex int) (*PostRef, error) {
+	if len(txID) == 0 {
+		return nil, nil
+	}
+	id, err := ids.FromString(txID)
+	if err != nil {
+		return nil, err
+	}
+	return &PostRef{TxID: id, ActionIndex: actionIndex}, nil
+}
+
+// parsePostRef parses a reference to a post: a transaction ID, which refers
+// to the first post of the transaction, optionally followed by a slash and
+// the action index of the post.
+func parsePostRef(ref string) (string, *int, error) {
+	txID, index, ok := strings.Cut(ref, "/")
+	if _, err := ids.FromString(txID); err != nil {
+		return "", nil, fmt.Errorf("%w: txID %q", errInvalidPostRef, txID)
+	}
+	if !ok {
+		return txID, nil, nil
+	}
+	actionIndex, err := strconv.Atoi(index)
+	if err != nil || actionIndex < 0 {
+		return "", nil, fmt.Errorf("%w: action index %q", errInvalidPostRef, index)
+	}
+	return txID, &actionIndex, nil
+}
+
+// findPost finds the post [ref] refers to among the visible posts indexed so
+// far and [pending], the posts of the block being indexed. The caller must
+// hold the lock.
+func (m *Manager) findPost(ref string, pending []database.FeedObject) (*database.FeedObject, error) {
+	txID, actionIndex, err := parsePostRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		feed := &pending[i]
+		if feed.TxID == txID && !feed.Hidden && (actionIndex == nil || feed.ActionIndex == *actionIndex) {
+			return feed, nil
+		}
+	}
+	feed, err := m.getFeedByTxID(txID, actionIndex)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if feed.SubnetID != m.subnetID.String() || feed.ChainID != m.chainID.String() {
+		return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, ref)
+	}
+	return feed, nil
+}
+
+// findLivePost finds the post [ref] refers to like findPost, and fails with
+// ErrFeedNotFound if it was deleted, including by a revision [b] of the block
+// being indexed. The caller must hold the lock.
+func (m *Manager) findLivePost(ref string, pending []database.FeedObject, b *blockRevisions) (*database.FeedObject, error) {
+	post, err := m.findPost(ref, pending)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newPostRef(post.TxID, post.ActionIndex)
+	if err != nil {
+		return nil, err
+	}
+	if latest, ok := b.latest[*key]; ok {
+		post = latest
+	}
+	if post.Deleted {
+		return nil, fmt.Errorf("%w: %s was deleted", ErrFeedNotFound, key)
+	}
+	return post, nil
+}
+
+// resolveReply finds the parent of a reply to [replyTo], see findLivePost,
+// and returns the parent and the root of the thread. The caller must hold the
+// lock.
+func (m *Manager) resolveReply(replyTo string, pending []database.FeedObject, b *blockRevisions) (*PostRef, *PostRef, error) {
+	parent, err := m.findLivePost(replyTo, pending, b)
+	if errors.Is(err, ErrFeedNotFound) || errors.Is(err, errInvalidPostRef) {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidReply, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parentRef, err := newPostRef(parent.TxID, parent.ActionIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	rootRef, err := newPostRef(parent.RootTxID, parent.RootActionIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rootRef == nil {
+		rootRef = parentRef
+	}
+	return parentRef, rootRef, nil
+}
+
+// ThreadNode is a post and the replies to it, oldest first.
+type ThreadNode struct {
+	Post    *FeedObject   `json:"post"`
+	Replies []*ThreadNode `json:"replies"`
+}
+
+// GetThread returns the thread that contains the post made by action
+// [actionIndex] of [txID], or the first post of [txID] if it is nil. The
+// thread holds at most [limit] posts, capped at the configured feed size,
+// starting from the oldest. Replies to hidden posts are left out. If the
+// first post of the thread is hidden, the thread starts at the requested
+// post.
+func (m *Manager) GetThread(_ context.Context, txID string, actionIndex *int, limit int) (*ThreadNode, error) {
+	feed, err := m.findFeed(txID, actionIndex)
+	if err != nil {
+		return nil, err
+	}
+	post, err := toFeedObject(feed)
+	if err != nil {
+		m.log.Warn("Malformed feed", zap.String("TxID", feed.TxID), zap.Int("action", feed.ActionIndex), zap.Error(err))
+		return nil, err
+	}
+
+	root := post.Root
+	if root == nil {
+		root = &PostRef{TxID: post.TxID, ActionIndex: post.ActionIndex}
+	}
+	feeds, err := m.db.GetThread(root.TxID.String(), root.ActionIndex, m.pageSize(limit))
+	if err != nil {
+		m.log.Error("Failed to get thread from database", zap.Error(err))
+		return nil, err
+	}
+
+	nodes := map[PostRef]*ThreadNode{}
+	posts := m.toFeedObjects(feeds)
+	for _, post := range posts {
+		nodes[PostRef{post.TxID, post.ActionIndex}] = &ThreadNode{Post: post, Replies: []*ThreadNode{}}
+	}
+	for _, post := range posts {
+		if post.Parent == nil {
+			continue
+		}
+		if parent, ok := nodes[*post.Parent]; ok {
+			parent.Replies = append(parent.Replies, nodes[PostRef{post.TxID, post.ActionIndex}])
+		}
+	}
+	if node, ok := nodes[*root]; ok {
+		return node, nil
+	}
+	if node, ok := nodes[PostRef{post.TxID, post.ActionIndex}]; ok {
+		return node, nil
+	}
+	return &ThreadNode{Post: post, Replies: []*ThreadNode{}}, nil
+}
+
+// GetReplyCount returns the number of visible direct replies to the post
+// made by action [actionIndex] of [txID], or the first post of [txID] if it
+// is nil.
+func (m *Manager) GetReplyCount(_ context.Context, txID string, actionIndex *int) (int, error) {
+	feed, err := m.findFeed(txID, actionIndex)
+	if err != nil {
+		return 0, err
+	}
+	count, err := m.db.CountReplies(feed.TxID, feed.ActionIndex)
+	if err != nil {
+		m.log.Error("Failed to count replies", zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
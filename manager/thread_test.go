@@ -0,0 +1,128 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/chain"
+)
+
+func replyMemo(replyTo, message string) string {
+	return fmt.Sprintf(`{"replyTo":%q,"message":%q}`, replyTo, message)
+}
+
+func deleteMemo(txID string) string {
+	return fmt.Sprintf(`{"delete":%q}`, txID)
+}
+
+func ref(tx *chain.Transaction) *PostRef {
+	return &PostRef{TxID: tx.ID(), ActionIndex: 0}
+}
+
+func sameRef(a, b *PostRef) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// checkReply checks that [reply] was indexed with [parent] and [root].
+func checkReply(t *testing.T, m *Manager, reply *chain.Transaction, parent, root *PostRef) {
+	t.Helper()
+
+	post, err := m.GetFeedByTxID(context.Background(), reply.ID().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRef(post.Parent, parent) || !sameRef(post.Root, root) {
+		t.Fatalf("reply has parent %v and root %v, want %v and %v", post.Parent, post.Root, parent, root)
+	}
+}
+
+// checkRejected checks that the post of [tx] was not indexed.
+func checkRejected(t *testing.T, m *Manager, tx *chain.Transaction) {
+	t.Helper()
+
+	if post, err := m.GetFeedByTxID(context.Background(), tx.ID().String(), nil); !errors.Is(err, ErrFeedNotFound) {
+		t.Fatalf("indexed %+v (%v), want it rejected", post, err)
+	}
+}
+
+func TestReplyResolution(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+
+			post := testTransfer(t, m, 100, "post")
+			reply := testTransfer(t, m, 100, replyMemo(post.ID().String(), "reply"))
+			if err := processTxs(t, m, 1, post, reply); err != nil {
+				t.Fatal(err)
+			}
+			checkReply(t, m, post, nil, nil)
+			checkReply(t, m, reply, ref(post), ref(post))
+
+			// A reply to a reply is part of the thread of the first post.
+			nested := testTransfer(t, m, 100, replyMemo(reply.ID().String()+"/0", "nested"))
+			missing := testTransfer(t, m, 100, replyMemo(post.ID().String()+"/1", "missing action"))
+			if err := processTxs(t, m, 2, nested, missing); err != nil {
+				t.Fatal(err)
+			}
+			checkReply(t, m, nested, ref(reply), ref(post))
+			checkRejected(t, m, missing)
+
+			thread, err := m.GetThread(context.Background(), nested.ID().String(), nil, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if thread.Post.TxID != post.ID() || len(thread.Replies) != 1 || len(thread.Replies[0].Replies) != 1 || thread.Replies[0].Replies[0].Post.TxID != nested.ID() {
+				t.Fatalf("thread does not nest the replies under %s", post.ID())
+			}
+		})
+	}
+}
+
+func TestReplyToUnavailablePostRejected(t *testing.T) {
+	for name, db := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, db)
+			author, banned := testKey(t), testKey(t)
+			if err := m.BanAuthor(context.Background(), testAddress(banned), true, "admin", "", "spam"); err != nil {
+				t.Fatal(err)
+			}
+
+			hidden := testTransfer(t, m, 100, "hidden")
+			deleted := testTransferFrom(t, m, author, 100, "deleted")
+			deletedInBlock := testTransferFrom(t, m, author, 100, "deleted in block")
+			if err := processTxs(t, m, 1, hidden, deleted, deletedInBlock); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.HidePost(context.Background(), hidden.ID().String(), nil, true, "admin", "", "spam"); err != nil {
+				t.Fatal(err)
+			}
+			if err := processTxs(t, m, 2, testTransferFrom(t, m, author, 100, deleteMemo(deleted.ID().String()))); err != nil {
+				t.Fatal(err)
+			}
+
+			bannedPost := testTransferFrom(t, m, banned, 100, "banned")
+			replies := []*chain.Transaction{
+				testTransfer(t, m, 100, replyMemo(hidden.ID().String(), "to hidden")),
+				testTransfer(t, m, 100, replyMemo(deleted.ID().String(), "to deleted")),
+				testTransferFrom(t, m, author, 100, deleteMemo(deletedInBlock.ID().String())),
+				testTransfer(t, m, 100, replyMemo(deletedInBlock.ID().String(), "to deleted in block")),
+				bannedPost,
+				testTransfer(t, m, 100, replyMemo(bannedPost.ID().String(), "to hidden in block")),
+			}
+			if err := processTxs(t, m, 3, replies...); err != nil {
+				t.Fatal(err)
+			}
+			for _, reply := range []*chain.Transaction{replies[0], replies[1], replies[3], replies[5]} {
+				checkRejected(t, m, reply)
+			}
+		})
+	}
+}
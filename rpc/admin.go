@@ -0,0 +1,194 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklai-feed/config"
+)
+
+// AdminAuthWindow is how far the timestamp of a signed admin request may be
+// from the server clock. Nonces are remembered until their request leaves the
+// window, so a signed request cannot be replayed. They are only kept in
+// memory, so requests signed before the server started are refused.
+const AdminAuthWindow = 5 * time.Minute
+
+// AdminTokenIdentity is the identity of admin requests authenticated with the
+// admin token, which does not identify the admin.
+const AdminTokenIdentity = "adminToken"
+
+var ErrUnauthorized = errors.New("unauthorized user")
+
+// AdminAuth holds the credentials of an admin request: either a signature by
+// one of the configured admin keys over AdminMessage, or the admin token if
+// it is enabled.
+type AdminAuth struct {
+	PublicKey string `json:"publicKey,omitempty"` // hex
+	Nonce     string `json:"nonce,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"` // unix milliseconds
+	Signature string `json:"signature,omitempty"` // hex
+
+	AdminToken string `json:"adminToken,omitempty"`
+}
+
+// adminAuthFields are the JSON fields of AdminAuth, which are left out of
+// the signed arguments.
+var adminAuthFields = []string{"publicKey", "nonce", "timestamp", "signature", "adminToken"}
+
+// AdminArgsHash returns the hex SHA-256 of the canonical encoding of [args],
+// the arguments of an admin request: compact JSON without the AdminAuth
+// fields, with keys sorted and without HTML escaping.
+func AdminArgsHash(args any) (string, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return "", err
+	}
+	for _, field := range adminAuthFields {
+		delete(fields, field)
+	}
+	// Maps are encoded with sorted keys.
+	var canonical bytes.Buffer
+	enc := json.NewEncoder(&canonical)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fields); err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(bytes.TrimSuffix(canonical.Bytes(), []byte("\n")))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// AdminMessage returns the payload an admin key signs to call [method] with
+// the arguments hashed by AdminArgsHash into [argsHash].
+func AdminMessage(method, argsHash, nonce string, timestamp int64) []byte {
+	return []byte(fmt.Sprintf("nuklai-feed admin\n%s\n%s\n%s\n%d", method, argsHash, nonce, timestamp))
+}
+
+// AdminCredentials authenticate the admin requests of JSONRPCClient.
+type AdminCredentials interface {
+	// Authenticate returns the credentials of a call to [method] with [args].
+	Authenticate(method string, args any) (AdminAuth, error)
+}
+
+// AdminToken authenticates admin requests with the shared admin token, which
+// the server only accepts if ADMIN_TOKEN_ENABLED is set.
+type AdminToken string
+
+func (t AdminToken) Authenticate(string, any) (AdminAuth, error) {
+	return AdminAuth{AdminToken: string(t)}, nil
+}
+
+// AdminKey authenticates admin requests by signing them with an admin key.
+type AdminKey ed25519.PrivateKey
+
+func (k AdminKey) Authenticate(method string, args any) (AdminAuth, error) {
+	argsHash, err := AdminArgsHash(args)
+	if err != nil {
+		return AdminAuth{}, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return AdminAuth{}, err
+	}
+	pk := ed25519.PrivateKey(k).PublicKey()
+	auth := AdminAuth{
+		PublicKey: hex.EncodeToString(pk[:]),
+		Nonce:     hex.EncodeToString(nonce),
+		Timestamp: time.Now().UnixMilli(),
+	}
+	sig := ed25519.Sign(AdminMessage(method, argsHash, auth.Nonce, auth.Timestamp), ed25519.PrivateKey(k))
+	auth.Signature = hex.EncodeToString(sig[:])
+	return auth, nil
+}
+
+// adminAuthenticator checks the credentials of admin requests and remembers
+// the nonces of signed requests until they expire.
+type adminAuthenticator struct {
+	started int64 // unix milliseconds
+
+	l    sync.Mutex
+	seen map[string]int64 // public key and nonce to expiry, unix milliseconds
+}
+
+func newAdminAuthenticator() *adminAuthenticator {
+	return &adminAuthenticator{started: time.Now().UnixMilli(), seen: map[string]int64{}}
+}
+
+// authenticate checks [auth] for a call to [method] with [args] and returns
+// the identity of the caller: the hex public key of a signed request, or
+// AdminTokenIdentity if the admin token was used.
+func (a *adminAuthenticator) authenticate(config *config.Config, method string, args any, auth *AdminAuth) (string, error) {
+	if len(auth.Signature) == 0 {
+		if !config.AdminTokenEnabled || len(config.AdminToken) == 0 || subtle.ConstantTimeCompare([]byte(auth.AdminToken), []byte(config.AdminToken)) != 1 {
+			return "", ErrUnauthorized
+		}
+		return AdminTokenIdentity, nil
+	}
+
+	rawPK, err := hex.DecodeString(auth.PublicKey)
+	if err != nil || len(rawPK) != ed25519.PublicKeyLen {
+		return "", fmt.Errorf("%w: invalid public key", ErrUnauthorized)
+	}
+	rawSig, err := hex.DecodeString(auth.Signature)
+	if err != nil || len(rawSig) != ed25519.SignatureLen {
+		return "", fmt.Errorf("%w: invalid signature", ErrUnauthorized)
+	}
+	pk, sig := ed25519.PublicKey(rawPK), ed25519.Signature(rawSig)
+	if len(auth.Nonce) == 0 {
+		return "", fmt.Errorf("%w: missing nonce", ErrUnauthorized)
+	}
+	if !slices.Contains(config.AdminPublicKeys, pk) {
+		return "", ErrUnauthorized
+	}
+	now := time.Now().UnixMilli()
+	window := AdminAuthWindow.Milliseconds()
+	if auth.Timestamp < now-window || auth.Timestamp > now+window {
+		return "", fmt.Errorf("%w: timestamp outside of the allowed window", ErrUnauthorized)
+	}
+	// The nonces of requests signed before the server started are lost.
+	if auth.Timestamp < a.started {
+		return "", fmt.Errorf("%w: request signed before the server started", ErrUnauthorized)
+	}
+	argsHash, err := AdminArgsHash(args)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	if !ed25519.Verify(AdminMessage(method, argsHash, auth.Nonce, auth.Timestamp), pk, sig) {
+		return "", ErrUnauthorized
+	}
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for key, expiry := range a.seen {
+		if expiry < now {
+			delete(a.seen, key)
+		}
+	}
+	// The public key is not signed, so use its canonical encoding.
+	identity := hex.EncodeToString(rawPK)
+	key := identity + "/" + auth.Nonce
+	if _, ok := a.seen[key]; ok {
+		return "", fmt.Errorf("%w: nonce already used", ErrUnauthorized)
+	}
+	a.seen[key] = auth.Timestamp + window
+	return identity, nil
+}
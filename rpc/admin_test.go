@@ -0,0 +1,246 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklai-feed/config"
+)
+
+func newAdminKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	priv, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// signAdmin returns the credentials of a call to [method] with [args] signed
+// by [priv] with [nonce] at [timestamp].
+func signAdmin(t *testing.T, priv ed25519.PrivateKey, method string, args any, nonce string, timestamp int64) AdminAuth {
+	t.Helper()
+
+	argsHash, err := AdminArgsHash(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := priv.PublicKey()
+	sig := ed25519.Sign(AdminMessage(method, argsHash, nonce, timestamp), priv)
+	return AdminAuth{
+		PublicKey: hex.EncodeToString(pk[:]),
+		Nonce:     nonce,
+		Timestamp: timestamp,
+		Signature: hex.EncodeToString(sig[:]),
+	}
+}
+
+func TestAdminArgsHash(t *testing.T) {
+	args := &BanAuthorArgs{Address: "nuklai1author", Reason: "spam <script>"}
+	hash, err := AdminArgsHash(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The credentials are not part of the signed arguments.
+	withAuth := *args
+	withAuth.AdminAuth = AdminAuth{PublicKey: "00", Nonce: "nonce", Timestamp: 1, Signature: "00", AdminToken: "token"}
+	if got, err := AdminArgsHash(&withAuth); err != nil || got != hash {
+		t.Fatalf("hash with credentials = %s (%v), want %s", got, err, hash)
+	}
+	// The encoding does not depend on the order of the fields.
+	reordered := map[string]any{"reason": "spam <script>", "address": "nuklai1author"}
+	if got, err := AdminArgsHash(reordered); err != nil || got != hash {
+		t.Fatalf("hash of reordered arguments = %s (%v), want %s", got, err, hash)
+	}
+	tampered := *args
+	tampered.Reason = "spam"
+	if got, err := AdminArgsHash(&tampered); err != nil || got == hash {
+		t.Fatalf("hash of tampered arguments = %s (%v), want a different hash", got, err)
+	}
+}
+
+func TestAdminAuthenticate(t *testing.T) {
+	admin, other := newAdminKey(t), newAdminKey(t)
+	cfg := &config.Config{AdminPublicKeys: []ed25519.PublicKey{admin.PublicKey()}}
+	args := &BanAuthorArgs{Address: "nuklai1author", Reason: "spam"}
+	now := time.Now().UnixMilli()
+	window := AdminAuthWindow.Milliseconds()
+
+	tests := []struct {
+		name   string
+		method string
+		args   any
+		auth   AdminAuth
+		ok     bool
+	}{
+		{
+			name:   "valid",
+			method: "banAuthor",
+			args:   args,
+			auth:   signAdmin(t, admin, "banAuthor", args, "valid", now),
+			ok:     true,
+		},
+		{
+			name:   "unknown key",
+			method: "banAuthor",
+			args:   args,
+			auth:   signAdmin(t, other, "banAuthor", args, "unknown key", now),
+		},
+		{
+			name:   "wrong key",
+			method: "banAuthor",
+			args:   args,
+			auth: func() AdminAuth {
+				auth := signAdmin(t, other, "banAuthor", args, "wrong key", now)
+				pk := admin.PublicKey()
+				auth.PublicKey = hex.EncodeToString(pk[:])
+				return auth
+			}(),
+		},
+		{
+			name:   "tampered args",
+			method: "banAuthor",
+			args:   &BanAuthorArgs{Address: "nuklai1other", Reason: "spam"},
+			auth:   signAdmin(t, admin, "banAuthor", args, "tampered args", now),
+		},
+		{
+			name:   "other method",
+			method: "unbanAuthor",
+			args:   args,
+			auth:   signAdmin(t, admin, "banAuthor", args, "other method", now),
+		},
+		{
+			name:   "expired",
+			method: "banAuthor",
+			args:   args,
+			auth:   signAdmin(t, admin, "banAuthor", args, "expired", now-window-time.Second.Milliseconds()),
+		},
+		{
+			name:   "future",
+			method: "banAuthor",
+			args:   args,
+			auth:   signAdmin(t, admin, "banAuthor", args, "future", now+window+time.Minute.Milliseconds()),
+		},
+		{
+			name:   "missing nonce",
+			method: "banAuthor",
+			args:   args,
+			auth:   signAdmin(t, admin, "banAuthor", args, "", now),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAdminAuthenticator()
+			a.started = now - 2*window
+			identity, err := a.authenticate(cfg, tt.method, tt.args, &tt.auth)
+			if !tt.ok {
+				if !errors.Is(err, ErrUnauthorized) {
+					t.Fatalf("err = %v, want %v", err, ErrUnauthorized)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity != tt.auth.PublicKey {
+				t.Fatalf("identity = %s, want %s", identity, tt.auth.PublicKey)
+			}
+		})
+	}
+}
+
+func TestAdminNonceReplay(t *testing.T) {
+	admin := newAdminKey(t)
+	cfg := &config.Config{AdminPublicKeys: []ed25519.PublicKey{admin.PublicKey()}}
+	args := &ModerationLogArgs{Limit: 10}
+	a := newAdminAuthenticator()
+
+	auth := signAdmin(t, admin, "moderationLog", args, "nonce", time.Now().UnixMilli())
+	if _, err := a.authenticate(cfg, "moderationLog", args, &auth); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.authenticate(cfg, "moderationLog", args, &auth); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("replayed request: err = %v, want %v", err, ErrUnauthorized)
+	}
+	// A restarted server has lost the nonce, so it refuses the request too.
+	restarted := newAdminAuthenticator()
+	restarted.started = auth.Timestamp + 1
+	if _, err := restarted.authenticate(cfg, "moderationLog", args, &auth); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("request replayed after a restart: err = %v, want %v", err, ErrUnauthorized)
+	}
+	fresh := signAdmin(t, admin, "moderationLog", args, "nonce", restarted.started)
+	if _, err := restarted.authenticate(cfg, "moderationLog", args, &fresh); err != nil {
+		t.Fatalf("request signed after the restart: %v", err)
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	args := &ModerationLogArgs{Limit: 10}
+	auth := AdminAuth{AdminToken: "secret"}
+	a := newAdminAuthenticator()
+
+	cfg := &config.Config{AdminToken: "secret"}
+	if _, err := a.authenticate(cfg, "moderationLog", args, &auth); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("token accepted without opting in: err = %v", err)
+	}
+	cfg.AdminTokenEnabled = true
+	identity, err := a.authenticate(cfg, "moderationLog", args, &auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity != AdminTokenIdentity {
+		t.Fatalf("identity = %q, want %q", identity, AdminTokenIdentity)
+	}
+	wrong := AdminAuth{AdminToken: "guess"}
+	if _, err := a.authenticate(cfg, "moderationLog", args, &wrong); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("wrong token: err = %v, want %v", err, ErrUnauthorized)
+	}
+}
+
+// moderationManager records the moderation calls made by the server.
+type moderationManager struct {
+	Manager
+	config    *config.Config
+	moderator string
+	name      string
+}
+
+func (m *moderationManager) Config() *config.Config { return m.config }
+
+func (m *moderationManager) BanAuthor(_ context.Context, _ string, _ bool, moderator, moderatorName, _ string) error {
+	m.moderator, m.name = moderator, moderatorName
+	return nil
+}
+
+func TestModerationLogsVerifiedKey(t *testing.T) {
+	admin := newAdminKey(t)
+	m := &moderationManager{config: &config.Config{AdminPublicKeys: []ed25519.PublicKey{admin.PublicKey()}}}
+	server := NewJSONRPCServer(m)
+
+	args := &BanAuthorArgs{Address: "nuklai1author", ModeratorName: "someone else", Reason: "spam"}
+	auth, err := AdminKey(admin).Authenticate("banAuthor", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args.AdminAuth = auth
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.BanAuthor(req, args, new(ModerationReply)); err != nil {
+		t.Fatal(err)
+	}
+	if m.moderator != auth.PublicKey || m.name != "someone else" {
+		t.Fatalf("logged moderator %q named %q, want %q named %q", m.moderator, m.name, auth.PublicKey, "someone else")
+	}
+}
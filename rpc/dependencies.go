@@ -13,7 +13,19 @@ import (
 
 type Manager interface {
 	GetFeedInfo(context.Context) (codec.Address, uint64, error)
-	GetFeed(context.Context, string, string, int) ([]*manager.FeedObject, error)
-	UpdateNuklaiRPC(context.Context, string) error
+	GetFeed(context.Context, string, string, string, int) (*manager.FeedPage, error)
+	GetFeedByTxID(context.Context, string, *int) (*manager.FeedObject, error)
+	GetFeedByAddress(context.Context, string, string, int) (*manager.FeedPage, error)
+	GetFeeHistory(context.Context, int) ([]*manager.FeeEpoch, error)
+	GetFeedSince(context.Context, string, int) ([]*manager.FeedObject, error)
+	SearchFeed(context.Context, manager.FeedQuery, string, int) (*manager.FeedPage, error)
+	GetThread(context.Context, string, *int, int) (*manager.ThreadNode, error)
+	GetReplyCount(context.Context, string, *int) (int, error)
+	GetAuthorStats(context.Context, string) (*manager.AuthorStats, error)
+	Subscribe(manager.FeedFilter) *manager.Subscription
+	HidePost(context.Context, string, *int, bool, string, string, string) error
+	BanAuthor(context.Context, string, bool, string, string, string) error
+	GetModerationLog(context.Context, int) ([]*manager.ModerationEntry, error)
+	UpdateNuklaiRPC(context.Context, string, bool) error
 	Config() *config.Config
 }
@@ -40,7 +40,9 @@ func (cli *JSONRPCClient) FeedInfo(ctx context.Context) (string, uint64, error)
 	return resp.Address, resp.Fee, err
 }
 
-func (cli *JSONRPCClient) Feed(ctx context.Context, subnetID, chainID string, limit int) ([]*manager.FeedObject, error) {
+// Feed returns a page of posts starting at [cursor], or at the newest post if
+// [cursor] is empty, along with the cursors of the next and previous pages
+func (cli *JSONRPCClient) Feed(ctx context.Context, subnetID, chainID, cursor string, limit int) ([]*manager.FeedObject, string, string, error) {
 	resp := new(FeedReply)
 	err := cli.requester.SendRequest(
 		ctx,
@@ -48,24 +50,226 @@ func (cli *JSONRPCClient) Feed(ctx context.Context, subnetID, chainID string, li
 		&FeedArgs{
 			SubnetID: subnetID,
 			ChainID:  chainID,
+			Cursor:   cursor,
 			Limit:    limit,
 		},
 		resp,
 	)
+	return resp.Feed, resp.Next, resp.Prev, err
+}
+
+// FeedByTxID returns the post made by action [actionIndex] of [txID], or the
+// first post in the transaction if [actionIndex] is nil
+func (cli *JSONRPCClient) FeedByTxID(ctx context.Context, txID string, actionIndex *int) (*manager.FeedObject, error) {
+	resp := new(FeedByTxIDReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"feedByTxID",
+		&FeedByTxIDArgs{
+			TxID:        txID,
+			ActionIndex: actionIndex,
+		},
+		resp,
+	)
 	return resp.Feed, err
 }
 
-// UpdateNuklaiRPC updates the RPC url for Nuklai
-func (cli *JSONRPCClient) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl, adminToken string) (bool, error) {
-	resp := new(UpdateNuklaiRPCReply)
+// FeedByAddress returns a page of posts made by [address], paginated like Feed
+func (cli *JSONRPCClient) FeedByAddress(ctx context.Context, address, cursor string, limit int) ([]*manager.FeedObject, string, string, error) {
+	resp := new(FeedReply)
 	err := cli.requester.SendRequest(
 		ctx,
-		"updateNuklaiRPC",
-		&UpdateNuklaiRPCArgs{
-			NuklaiRPCUrl: newNuklaiRPCUrl,
-			AdminToken:   adminToken,
+		"feedByAddress",
+		&FeedByAddressArgs{
+			Address: address,
+			Cursor:  cursor,
+			Limit:   limit,
+		},
+		resp,
+	)
+	return resp.Feed, resp.Next, resp.Prev, err
+}
+
+// SearchFeed returns a page of posts matching [query], paginated like Feed
+func (cli *JSONRPCClient) SearchFeed(ctx context.Context, query manager.FeedQuery, cursor string, limit int) ([]*manager.FeedObject, string, string, error) {
+	resp := new(FeedReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"searchFeed",
+		&SearchFeedArgs{
+			Query:   query.Text,
+			Address: query.Address,
+			From:    query.From,
+			To:      query.To,
+			Cursor:  cursor,
+			Limit:   limit,
 		},
 		resp,
 	)
+	return resp.Feed, resp.Next, resp.Prev, err
+}
+
+// Thread returns the thread that contains the post made by action
+// [actionIndex] of [txID], or the first post of [txID] if it is nil
+func (cli *JSONRPCClient) Thread(ctx context.Context, txID string, actionIndex *int, limit int) (*manager.ThreadNode, error) {
+	resp := new(ThreadReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"thread",
+		&ThreadArgs{
+			TxID:        txID,
+			ActionIndex: actionIndex,
+			Limit:       limit,
+		},
+		resp,
+	)
+	return resp.Thread, err
+}
+
+// ReplyCount returns the number of direct replies to the post made by action
+// [actionIndex] of [txID], or the first post of [txID] if it is nil
+func (cli *JSONRPCClient) ReplyCount(ctx context.Context, txID string, actionIndex *int) (int, error) {
+	resp := new(ReplyCountReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"replyCount",
+		&FeedByTxIDArgs{
+			TxID:        txID,
+			ActionIndex: actionIndex,
+		},
+		resp,
+	)
+	return resp.Replies, err
+}
+
+// AuthorStats returns the number of posts of [address] and the reactions and
+// tips they received
+func (cli *JSONRPCClient) AuthorStats(ctx context.Context, address string) (*manager.AuthorStats, error) {
+	resp := new(AuthorStatsReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"authorStats",
+		&AuthorStatsArgs{
+			Address: address,
+		},
+		resp,
+	)
+	return resp.Stats, err
+}
+
+// FeeHistory returns the most recent fee epochs, newest first
+func (cli *JSONRPCClient) FeeHistory(ctx context.Context, limit int) ([]*manager.FeeEpoch, error) {
+	resp := new(FeeHistoryReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"feeHistory",
+		&FeeHistoryArgs{
+			Limit: limit,
+		},
+		resp,
+	)
+	return resp.Epochs, err
+}
+
+// UpdateNuklaiRPC updates the RPC url for Nuklai. [force] allows switching
+// to an RPC on another network or chain
+func (cli *JSONRPCClient) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string, force bool, admin AdminCredentials) (bool, error) {
+	args := &UpdateNuklaiRPCArgs{
+		NuklaiRPCUrl: newNuklaiRPCUrl,
+		Force:        force,
+	}
+	auth, err := admin.Authenticate("updateNuklaiRPC", args)
+	if err != nil {
+		return false, err
+	}
+	args.AdminAuth = auth
+	resp := new(UpdateNuklaiRPCReply)
+	err = cli.requester.SendRequest(
+		ctx,
+		"updateNuklaiRPC",
+		args,
+		resp,
+	)
+	return resp.Success, err
+}
+
+// HidePost hides the posts made in [txID], or only the post made by action
+// [actionIndex] if it is not nil
+func (cli *JSONRPCClient) HidePost(ctx context.Context, txID string, actionIndex *int, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	return cli.hidePost(ctx, "hidePost", txID, actionIndex, moderatorName, reason, admin)
+}
+
+// UnhidePost reverts HidePost
+func (cli *JSONRPCClient) UnhidePost(ctx context.Context, txID string, actionIndex *int, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	return cli.hidePost(ctx, "unhidePost", txID, actionIndex, moderatorName, reason, admin)
+}
+
+func (cli *JSONRPCClient) hidePost(ctx context.Context, method, txID string, actionIndex *int, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	args := &HidePostArgs{
+		TxID:          txID,
+		ActionIndex:   actionIndex,
+		ModeratorName: moderatorName,
+		Reason:        reason,
+	}
+	auth, err := admin.Authenticate(method, args)
+	if err != nil {
+		return false, err
+	}
+	args.AdminAuth = auth
+	return cli.moderate(ctx, method, args)
+}
+
+// BanAuthor hides every future post made by [address]
+func (cli *JSONRPCClient) BanAuthor(ctx context.Context, address, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	return cli.banAuthor(ctx, "banAuthor", address, moderatorName, reason, admin)
+}
+
+// UnbanAuthor reverts BanAuthor
+func (cli *JSONRPCClient) UnbanAuthor(ctx context.Context, address, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	return cli.banAuthor(ctx, "unbanAuthor", address, moderatorName, reason, admin)
+}
+
+func (cli *JSONRPCClient) banAuthor(ctx context.Context, method, address, moderatorName, reason string, admin AdminCredentials) (bool, error) {
+	args := &BanAuthorArgs{
+		Address:       address,
+		ModeratorName: moderatorName,
+		Reason:        reason,
+	}
+	auth, err := admin.Authenticate(method, args)
+	if err != nil {
+		return false, err
+	}
+	args.AdminAuth = auth
+	return cli.moderate(ctx, method, args)
+}
+
+func (cli *JSONRPCClient) moderate(ctx context.Context, method string, args any) (bool, error) {
+	resp := new(ModerationReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		method,
+		args,
+		resp,
+	)
 	return resp.Success, err
 }
+
+// ModerationLog returns the most recent moderation actions, newest first
+func (cli *JSONRPCClient) ModerationLog(ctx context.Context, limit int, admin AdminCredentials) ([]*manager.ModerationEntry, error) {
+	args := &ModerationLogArgs{
+		Limit: limit,
+	}
+	auth, err := admin.Authenticate("moderationLog", args)
+	if err != nil {
+		return nil, err
+	}
+	args.AdminAuth = auth
+	resp := new(ModerationLogReply)
+	err = cli.requester.SendRequest(
+		ctx,
+		"moderationLog",
+		args,
+		resp,
+	)
+	return resp.Entries, err
+}
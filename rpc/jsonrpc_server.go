@@ -4,7 +4,6 @@
 package rpc
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/ava-labs/hypersdk/codec"
@@ -13,11 +12,12 @@ import (
 )
 
 type JSONRPCServer struct {
-	m Manager
+	m     Manager
+	admin *adminAuthenticator
 }
 
 func NewJSONRPCServer(m Manager) *JSONRPCServer {
-	return &JSONRPCServer{m}
+	return &JSONRPCServer{m, newAdminAuthenticator()}
 }
 
 type FeedInfoReply struct {
@@ -38,15 +38,40 @@ func (j *JSONRPCServer) FeedInfo(req *http.Request, _ *struct{}, reply *FeedInfo
 type FeedArgs struct {
 	SubnetID string `json:"subnetID"`
 	ChainID  string `json:"chainID"`
+	Cursor   string `json:"cursor"`
 	Limit    int    `json:"limit"`
 }
 
 type FeedReply struct {
 	Feed []*manager.FeedObject `json:"feed"`
+	Next string                `json:"next,omitempty"`
+	Prev string                `json:"prev,omitempty"`
 }
 
 func (j *JSONRPCServer) Feed(req *http.Request, args *FeedArgs, reply *FeedReply) (err error) {
-	feed, err := j.m.GetFeed(req.Context(), args.SubnetID, args.ChainID, args.Limit)
+	page, err := j.m.GetFeed(req.Context(), args.SubnetID, args.ChainID, args.Cursor, args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Feed = page.Feed
+	reply.Next = page.Next
+	reply.Prev = page.Prev
+	return nil
+}
+
+type FeedByTxIDArgs struct {
+	TxID string `json:"txID"`
+	// ActionIndex selects a post when the transaction made several. The
+	// first post is returned if it is omitted.
+	ActionIndex *int `json:"actionIndex,omitempty"`
+}
+
+type FeedByTxIDReply struct {
+	Feed *manager.FeedObject `json:"feed"`
+}
+
+func (j *JSONRPCServer) FeedByTxID(req *http.Request, args *FeedByTxIDArgs, reply *FeedByTxIDReply) (err error) {
+	feed, err := j.m.GetFeedByTxID(req.Context(), args.TxID, args.ActionIndex)
 	if err != nil {
 		return err
 	}
@@ -54,23 +79,230 @@ func (j *JSONRPCServer) Feed(req *http.Request, args *FeedArgs, reply *FeedReply
 	return nil
 }
 
+type FeedByAddressArgs struct {
+	Address string `json:"address"`
+	Cursor  string `json:"cursor"`
+	Limit   int    `json:"limit"`
+}
+
+func (j *JSONRPCServer) FeedByAddress(req *http.Request, args *FeedByAddressArgs, reply *FeedReply) (err error) {
+	page, err := j.m.GetFeedByAddress(req.Context(), args.Address, args.Cursor, args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Feed = page.Feed
+	reply.Next = page.Next
+	reply.Prev = page.Prev
+	return nil
+}
+
+type SearchFeedArgs struct {
+	Query   string `json:"query"`
+	Address string `json:"address"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+	Cursor  string `json:"cursor"`
+	Limit   int    `json:"limit"`
+}
+
+func (j *JSONRPCServer) SearchFeed(req *http.Request, args *SearchFeedArgs, reply *FeedReply) (err error) {
+	query := manager.FeedQuery{
+		Text:    args.Query,
+		Address: args.Address,
+		From:    args.From,
+		To:      args.To,
+	}
+	page, err := j.m.SearchFeed(req.Context(), query, args.Cursor, args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Feed = page.Feed
+	reply.Next = page.Next
+	reply.Prev = page.Prev
+	return nil
+}
+
+type ThreadArgs struct {
+	TxID string `json:"txID"`
+	// ActionIndex selects a post when the transaction made several. The
+	// first post is used if it is omitted.
+	ActionIndex *int `json:"actionIndex,omitempty"`
+	Limit       int  `json:"limit"`
+}
+
+type ThreadReply struct {
+	Thread *manager.ThreadNode `json:"thread"`
+}
+
+// Thread returns the thread that contains a post, as a tree rooted at the
+// first post of the thread.
+func (j *JSONRPCServer) Thread(req *http.Request, args *ThreadArgs, reply *ThreadReply) (err error) {
+	thread, err := j.m.GetThread(req.Context(), args.TxID, args.ActionIndex, args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Thread = thread
+	return nil
+}
+
+type ReplyCountReply struct {
+	Replies int `json:"replies"`
+}
+
+func (j *JSONRPCServer) ReplyCount(req *http.Request, args *FeedByTxIDArgs, reply *ReplyCountReply) (err error) {
+	count, err := j.m.GetReplyCount(req.Context(), args.TxID, args.ActionIndex)
+	if err != nil {
+		return err
+	}
+	reply.Replies = count
+	return nil
+}
+
+type AuthorStatsArgs struct {
+	Address string `json:"address"`
+}
+
+type AuthorStatsReply struct {
+	Stats *manager.AuthorStats `json:"stats"`
+}
+
+// AuthorStats returns the number of posts of an author and the reactions and
+// tips they received.
+func (j *JSONRPCServer) AuthorStats(req *http.Request, args *AuthorStatsArgs, reply *AuthorStatsReply) (err error) {
+	stats, err := j.m.GetAuthorStats(req.Context(), args.Address)
+	if err != nil {
+		return err
+	}
+	reply.Stats = stats
+	return nil
+}
+
+type FeeHistoryArgs struct {
+	Limit int `json:"limit"`
+}
+
+type FeeHistoryReply struct {
+	Epochs []*manager.FeeEpoch `json:"epochs"`
+}
+
+func (j *JSONRPCServer) FeeHistory(req *http.Request, args *FeeHistoryArgs, reply *FeeHistoryReply) (err error) {
+	epochs, err := j.m.GetFeeHistory(req.Context(), args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Epochs = epochs
+	return nil
+}
+
 type UpdateNuklaiRPCArgs struct {
 	NuklaiRPCUrl string `json:"nuklaiRPCUrl"`
-	AdminToken   string `json:"adminToken"`
+	// Force allows switching to an endpoint on another network or chain.
+	Force bool `json:"force,omitempty"`
+	AdminAuth
 }
 
 type UpdateNuklaiRPCReply struct {
 	Success bool `json:"success"`
 }
 
+// authorize checks the credentials [auth] of an admin request to [method]
+// with [args] and returns the verified identity of the caller.
+func (j *JSONRPCServer) authorize(method string, args any, auth *AdminAuth) (string, error) {
+	return j.admin.authenticate(j.m.Config(), method, args, auth)
+}
+
 func (j *JSONRPCServer) UpdateNuklaiRPC(req *http.Request, args *UpdateNuklaiRPCArgs, reply *UpdateNuklaiRPCReply) error {
-	if args.AdminToken != j.m.Config().AdminToken {
-		return errors.New("unauthorized user")
+	if _, err := j.authorize("updateNuklaiRPC", args, &args.AdminAuth); err != nil {
+		return err
+	}
+	err := j.m.UpdateNuklaiRPC(req.Context(), args.NuklaiRPCUrl, args.Force)
+	if err != nil {
+		return err
+	}
+	reply.Success = true
+	return nil
+}
+
+type HidePostArgs struct {
+	TxID string `json:"txID"`
+	// ActionIndex selects a single post when the transaction made several.
+	// Every post in the transaction is moderated if it is omitted.
+	ActionIndex *int `json:"actionIndex,omitempty"`
+	// ModeratorName is recorded next to the verified identity of the caller.
+	ModeratorName string `json:"moderatorName,omitempty"`
+	Reason        string `json:"reason"`
+	AdminAuth
+}
+
+type ModerationReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) HidePost(req *http.Request, args *HidePostArgs, reply *ModerationReply) error {
+	return j.hidePost(req, "hidePost", args, true, reply)
+}
+
+func (j *JSONRPCServer) UnhidePost(req *http.Request, args *HidePostArgs, reply *ModerationReply) error {
+	return j.hidePost(req, "unhidePost", args, false, reply)
+}
+
+func (j *JSONRPCServer) hidePost(req *http.Request, method string, args *HidePostArgs, hidden bool, reply *ModerationReply) error {
+	identity, err := j.authorize(method, args, &args.AdminAuth)
+	if err != nil {
+		return err
+	}
+	if err := j.m.HidePost(req.Context(), args.TxID, args.ActionIndex, hidden, identity, args.ModeratorName, args.Reason); err != nil {
+		return err
 	}
-	err := j.m.UpdateNuklaiRPC(req.Context(), args.NuklaiRPCUrl)
+	reply.Success = true
+	return nil
+}
+
+type BanAuthorArgs struct {
+	Address string `json:"address"`
+	// ModeratorName is recorded next to the verified identity of the caller.
+	ModeratorName string `json:"moderatorName,omitempty"`
+	Reason        string `json:"reason"`
+	AdminAuth
+}
+
+func (j *JSONRPCServer) BanAuthor(req *http.Request, args *BanAuthorArgs, reply *ModerationReply) error {
+	return j.banAuthor(req, "banAuthor", args, true, reply)
+}
+
+func (j *JSONRPCServer) UnbanAuthor(req *http.Request, args *BanAuthorArgs, reply *ModerationReply) error {
+	return j.banAuthor(req, "unbanAuthor", args, false, reply)
+}
+
+func (j *JSONRPCServer) banAuthor(req *http.Request, method string, args *BanAuthorArgs, banned bool, reply *ModerationReply) error {
+	identity, err := j.authorize(method, args, &args.AdminAuth)
 	if err != nil {
 		return err
 	}
+	if err := j.m.BanAuthor(req.Context(), args.Address, banned, identity, args.ModeratorName, args.Reason); err != nil {
+		return err
+	}
 	reply.Success = true
 	return nil
 }
+
+type ModerationLogArgs struct {
+	Limit int `json:"limit"`
+	AdminAuth
+}
+
+type ModerationLogReply struct {
+	Entries []*manager.ModerationEntry `json:"entries"`
+}
+
+func (j *JSONRPCServer) ModerationLog(req *http.Request, args *ModerationLogArgs, reply *ModerationLogReply) error {
+	if _, err := j.authorize("moderationLog", args, &args.AdminAuth); err != nil {
+		return err
+	}
+	entries, err := j.m.GetModerationLog(req.Context(), args.Limit)
+	if err != nil {
+		return err
+	}
+	reply.Entries = entries
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]any
+
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// openAPIDocument returns the OpenAPI 3 description of the REST API. It is
+// generated from restRoutes and the response types, so it cannot drift from
+// the handlers.
+func openAPIDocument() map[string]any {
+	openAPIOnce.Do(func() {
+		paths := map[string]any{}
+		for _, route := range restRoutes {
+			var params []any
+			for _, segment := range strings.Split(route.pattern, "/") {
+				if name, ok := strings.CutPrefix(segment, "{"); ok {
+					params = append(params, map[string]any{
+						"name":     strings.TrimSuffix(name, "}"),
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]any{"type": "string"},
+					})
+				}
+			}
+			for _, param := range route.query {
+				params = append(params, map[string]any{
+					"name":        param.name,
+					"in":          "query",
+					"description": param.description,
+					"schema":      map[string]any{"type": param.kind},
+				})
+			}
+			operation := map[string]any{
+				"summary": route.summary,
+				"responses": map[string]any{
+					"200":     jsonResponse("OK", reflect.TypeOf(route.response)),
+					"default": jsonResponse("Error", reflect.TypeOf(ErrorResponse{})),
+				},
+			}
+			if len(params) > 0 {
+				operation["parameters"] = params
+			}
+			paths[strings.TrimSuffix(RESTEndpoint, "/")+route.pattern] = map[string]any{"get": operation}
+		}
+		openAPIDoc = map[string]any{
+			"openapi": "3.0.3",
+			"info": map[string]any{
+				"title":   "Nuklai Feed",
+				"version": "v1",
+			},
+			"paths": paths,
+		}
+	})
+	return openAPIDoc
+}
+
+func jsonResponse(description string, t reflect.Type) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schemaOf(t)},
+		},
+	}
+}
+
+// schemaOf returns the JSON schema of values of type [t] encoded with
+// encoding/json.
+func schemaOf(t reflect.Type) map[string]any {
+	if t.Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Uint, reflect.Uint64:
+		// Amounts can exceed int64, so they are not declared as int64.
+		return map[string]any{"type": "integer", "format": "uint64", "minimum": 0}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if len(name) == 0 {
+				name = field.Name
+			}
+			properties[name] = schemaOf(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
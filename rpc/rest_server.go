@@ -0,0 +1,258 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-feed/manager"
+	"github.com/nuklai/nuklaivm/consts"
+)
+
+const (
+	RESTEndpoint = "/v1/"
+)
+
+// errInvalidParam is returned when a path or query parameter is malformed.
+var errInvalidParam = errors.New("invalid parameter")
+
+// RESTServer serves a read-only REST API over the same Manager as the
+// JSON-RPC server. Routes are described by restRoutes, which also generates
+// the OpenAPI document served at /v1/openapi.json.
+type RESTServer struct {
+	m Manager
+}
+
+func NewRESTServer(m Manager) *RESTServer {
+	return &RESTServer{m}
+}
+
+type PostsResponse struct {
+	Posts []*manager.FeedObject `json:"posts"`
+	Next  string                `json:"next,omitempty"`
+	Prev  string                `json:"prev,omitempty"`
+}
+
+type FeeResponse struct {
+	Address string `json:"address"`
+	Fee     uint64 `json:"fee"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func newPostsResponse(page *manager.FeedPage) *PostsResponse {
+	posts := page.Feed
+	if posts == nil {
+		posts = []*manager.FeedObject{}
+	}
+	return &PostsResponse{Posts: posts, Next: page.Next, Prev: page.Prev}
+}
+
+// restParam is a query parameter accepted by a route.
+type restParam struct {
+	name        string
+	kind        string // OpenAPI type of the parameter
+	description string
+}
+
+var (
+	cursorParam = restParam{"cursor", "string", "Cursor returned as next or prev by a previous page"}
+	limitParam  = restParam{"limit", "integer", "Maximum number of posts, capped at the configured feed size"}
+)
+
+type restRoute struct {
+	pattern  string // path below /v1, with {name} path parameters
+	summary  string
+	query    []restParam
+	response any // a value of the response type, used for the schema
+	handle   func(s *RESTServer, r *http.Request, params map[string]string) (any, error)
+}
+
+var restRoutes = []restRoute{
+	{
+		pattern:  "/posts",
+		summary:  "List the latest posts, newest first",
+		query:    []restParam{{"subnetID", "string", "Subnet of the posts, defaults to the indexed subnet"}, {"chainID", "string", "Chain of the posts, defaults to the indexed chain"}, cursorParam, limitParam},
+		response: PostsResponse{},
+		handle:   (*RESTServer).listPosts,
+	},
+	{
+		pattern:  "/posts/{txID}",
+		summary:  "Get a post by transaction ID",
+		query:    []restParam{{"actionIndex", "integer", "Action of the transaction that made the post, defaults to the first post"}},
+		response: manager.FeedObject{},
+		handle:   (*RESTServer).getPost,
+	},
+	{
+		pattern:  "/authors/{address}/posts",
+		summary:  "List the posts of an author, newest first",
+		query:    []restParam{cursorParam, limitParam},
+		response: PostsResponse{},
+		handle:   (*RESTServer).listAuthorPosts,
+	},
+	{
+		pattern:  "/authors/{address}/stats",
+		summary:  "Get the number of posts of an author and the reactions and tips they received",
+		response: manager.AuthorStats{},
+		handle:   (*RESTServer).getAuthorStats,
+	},
+	{
+		pattern:  "/fee",
+		summary:  "Get the recipient address and the current fee",
+		response: FeeResponse{},
+		handle:   (*RESTServer).getFee,
+	},
+}
+
+// match returns the path parameters of [path] if it matches [pattern].
+func match(pattern, path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range want {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			if len(got[i]) == 0 {
+				return nil, false
+			}
+			params[strings.TrimSuffix(name, "}")] = got[i]
+			continue
+		}
+		if segment != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *RESTServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{"method not allowed"})
+		return
+	}
+	path := "/" + strings.TrimPrefix(r.URL.Path, RESTEndpoint)
+	if path == "/openapi.json" {
+		writeJSON(w, http.StatusOK, openAPIDocument())
+		return
+	}
+	for _, route := range restRoutes {
+		params, ok := match(route.pattern, path)
+		if !ok {
+			continue
+		}
+		resp, err := route.handle(s, r, params)
+		if err != nil {
+			writeJSON(w, errorStatus(err), &ErrorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	writeJSON(w, http.StatusNotFound, &ErrorResponse{"not found"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// errorStatus maps [err] to an HTTP status code.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, manager.ErrFeedNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidParam),
+		errors.Is(err, manager.ErrInvalidCursor),
+		errors.Is(err, manager.ErrChainMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func queryInt(r *http.Request, name string) (int, bool, error) {
+	raw := r.URL.Query().Get(name)
+	if len(raw) == 0 {
+		return 0, false, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, false, fmt.Errorf("%w: %s must be a non-negative integer", errInvalidParam, name)
+	}
+	return v, true, nil
+}
+
+func (s *RESTServer) listPosts(r *http.Request, _ map[string]string) (any, error) {
+	limit, _, err := queryInt(r, "limit")
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	page, err := s.m.GetFeed(r.Context(), query.Get("subnetID"), query.Get("chainID"), query.Get("cursor"), limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPostsResponse(page), nil
+}
+
+func (s *RESTServer) getPost(r *http.Request, params map[string]string) (any, error) {
+	txID := params["txID"]
+	if _, err := ids.FromString(txID); err != nil {
+		return nil, fmt.Errorf("%w: txID %q", errInvalidParam, txID)
+	}
+	actionIndex, ok, err := queryInt(r, "actionIndex")
+	if err != nil {
+		return nil, err
+	}
+	var index *int
+	if ok {
+		index = &actionIndex
+	}
+	return s.m.GetFeedByTxID(r.Context(), txID, index)
+}
+
+func (s *RESTServer) listAuthorPosts(r *http.Request, params map[string]string) (any, error) {
+	address := params["address"]
+	if _, err := codec.ParseAddressBech32(consts.HRP, address); err != nil {
+		return nil, fmt.Errorf("%w: address %q", errInvalidParam, address)
+	}
+	limit, _, err := queryInt(r, "limit")
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.m.GetFeedByAddress(r.Context(), address, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPostsResponse(page), nil
+}
+
+func (s *RESTServer) getAuthorStats(r *http.Request, params map[string]string) (any, error) {
+	address := params["address"]
+	if _, err := codec.ParseAddressBech32(consts.HRP, address); err != nil {
+		return nil, fmt.Errorf("%w: address %q", errInvalidParam, address)
+	}
+	return s.m.GetAuthorStats(r.Context(), address)
+}
+
+func (s *RESTServer) getFee(r *http.Request, _ map[string]string) (any, error) {
+	addr, fee, err := s.m.GetFeedInfo(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return &FeeResponse{Address: codec.MustAddressBech32(consts.HRP, addr), Fee: fee}, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nuklai/nuklai-feed/manager"
+)
+
+const (
+	EventsEndpoint = "/feed/events"
+
+	sseKeepAlive = 30 * time.Second
+	// sseMaxReplay is roughly how many posts are read back for a
+	// reconnecting client before it is told to reset.
+	sseMaxReplay = 1000
+)
+
+// EventStreamServer streams new posts as Server-Sent Events. Every event has
+// the post's cursor as its ID, so a reconnecting client that sends
+// Last-Event-ID first receives every post it missed from the database. A
+// client that missed more than sseMaxReplay posts instead receives a reset
+// event, which clears its last event ID, and is disconnected: it should
+// reload the feed before reconnecting. The stream accepts the same filters as
+// the WebSocketServer.
+type EventStreamServer struct {
+	m Manager
+}
+
+func NewEventStreamServer(m Manager) *EventStreamServer {
+	return &EventStreamServer{m}
+}
+
+type postKey struct {
+	txID        string
+	actionIndex int
+}
+
+func (s *EventStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFeedFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// EventSource only sends Last-Event-ID when reconnecting, so clients can
+	// also pass it as a query parameter on their first connection.
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if len(lastEventID) == 0 {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	// Subscribe before replaying so no post is lost in between.
+	sub := s.m.Subscribe(*filter)
+	defer sub.Close()
+
+	ctx := r.Context()
+	var feed []*manager.FeedObject
+	if len(lastEventID) > 0 {
+		feed, err = s.m.GetFeedSince(ctx, lastEventID, 0)
+		if err != nil {
+			if errors.Is(err, manager.ErrInvalidCursor) {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Streams outlive the server's write timeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// Missed posts are written page by page. Posts indexed during the replay
+	// are also received by the subscription; they are skipped until the first
+	// post that was not replayed, after which the set is dropped.
+	replayed := map[postKey]struct{}{}
+	for scanned := 0; len(feed) > 0; {
+		if scanned >= sseMaxReplay {
+			_ = writeReset(w)
+			_ = rc.Flush()
+			return
+		}
+		for _, post := range feed {
+			if !filter.Match(post) {
+				continue
+			}
+			replayed[postKey{post.TxID.String(), post.ActionIndex}] = struct{}{}
+			if err := writeEvent(w, post); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		scanned += len(feed)
+		feed, err = s.m.GetFeedSince(ctx, feed[len(feed)-1].Cursor(), 0)
+		if err != nil {
+			// The client resumes from its last event when it reconnects.
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case post, ok := <-sub.C:
+			if !ok {
+				// The client fell behind. It resumes from its last event when it
+				// reconnects.
+				return
+			}
+			if _, ok := replayed[postKey{post.TxID.String(), post.ActionIndex}]; ok {
+				continue
+			}
+			replayed = nil
+			if err := writeEvent(w, post); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, post *manager.FeedObject) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: post\ndata: %s\n\n", post.Cursor(), data)
+	return err
+}
+
+// writeReset writes a reset event. Its empty ID clears the client's last
+// event ID, so it reconnects without replaying.
+func writeReset(w http.ResponseWriter) error {
+	_, err := fmt.Fprint(w, "id: \nevent: reset\ndata: {}\n\n")
+	return err
+}
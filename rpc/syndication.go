@@ -0,0 +1,313 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-feed/manager"
+	"github.com/nuklai/nuklaivm/consts"
+)
+
+const (
+	RSSEndpoint      = "/feed.rss"
+	AtomEndpoint     = "/feed.atom"
+	JSONFeedEndpoint = "/feed.json"
+
+	syndicationTitle = "Nuklai Feed"
+	itemTitleLength  = 80
+)
+
+// SyndicationServer renders the latest posts as RSS 2.0, Atom and JSON Feed
+// 1.1 documents. The address query parameter selects the posts of a single
+// author and limit caps the number of items.
+type SyndicationServer struct {
+	m Manager
+}
+
+func NewSyndicationServer(m Manager) *SyndicationServer {
+	return &SyndicationServer{m}
+}
+
+func (s *SyndicationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); len(raw) > 0 {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit %q", raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		address = r.URL.Query().Get("address")
+		title   = syndicationTitle
+		page    *manager.FeedPage
+		err     error
+	)
+	if len(address) > 0 {
+		title += ": " + address
+		if _, err = codec.ParseAddressBech32(consts.HRP, address); err != nil {
+			err = fmt.Errorf("%w: address %q", errInvalidParam, address)
+		} else {
+			page, err = s.m.GetFeedByAddress(r.Context(), address, "", limit)
+		}
+	} else {
+		page, err = s.m.GetFeed(r.Context(), "", "", "", limit)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	doc := &syndication{
+		Title:   title,
+		HomeURL: fmt.Sprintf("%s://%s/", scheme, r.Host),
+		FeedURL: fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI()),
+		// Deleted posts have nothing left to syndicate.
+		Posts: slices.DeleteFunc(page.Feed, func(post *manager.FeedObject) bool { return post.Deleted }),
+	}
+	switch r.URL.Path {
+	case RSSEndpoint:
+		writeXML(w, "application/rss+xml; charset=utf-8", doc.rss())
+	case AtomEndpoint:
+		writeXML(w, "application/atom+xml; charset=utf-8", doc.atom())
+	case JSONFeedEndpoint:
+		w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(doc.jsonFeed())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// syndication is a list of posts rendered in one of the feed formats.
+type syndication struct {
+	Title   string
+	HomeURL string
+	FeedURL string
+	Posts   []*manager.FeedObject
+}
+
+func (s *syndication) updated() time.Time {
+	if len(s.Posts) == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return postTime(s.Posts[0])
+}
+
+// postTime returns when [post] was published. Block timestamps are in
+// milliseconds.
+func postTime(post *manager.FeedObject) time.Time {
+	return time.UnixMilli(post.Timestamp).UTC()
+}
+
+// postGUID identifies [post] by its transaction ID. Later posts made by the
+// same transaction are suffixed with their action index.
+func postGUID(post *manager.FeedObject) string {
+	if post.ActionIndex == 0 {
+		return post.TxID.String()
+	}
+	return fmt.Sprintf("%s/%d", post.TxID, post.ActionIndex)
+}
+
+// postTitle returns the start of the message of [post].
+func postTitle(post *manager.FeedObject) string {
+	message := post.Content.Message
+	if utf8.RuneCountInString(message) <= itemTitleLength {
+		return message
+	}
+	runes := []rune(message)
+	return string(runes[:itemTitleLength-1]) + "…"
+}
+
+// postLink returns the link of [post], or an empty string unless it is an
+// absolute http or https URL. Readers follow links from feeds, so other
+// schemes such as javascript: or data: are dropped.
+func postLink(post *manager.FeedObject) string {
+	u, err := url.Parse(post.Content.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return ""
+	}
+	return u.String()
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (s *syndication) rss() *rssDocument {
+	doc := &rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         s.Title,
+			Link:          s.HomeURL,
+			Description:   "Latest posts on " + s.Title,
+			LastBuildDate: s.updated().Format(time.RFC1123Z),
+		},
+	}
+	for _, post := range s.Posts {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       postTitle(post),
+			Link:        postLink(post),
+			Description: post.Content.Message,
+			GUID:        rssGUID{Value: postGUID(post)},
+			PubDate:     postTime(post).Format(time.RFC1123Z),
+			// RSS authors must be email addresses, so the author's address is
+			// exposed as a category instead.
+			Category: post.Address,
+		})
+	}
+	return doc
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    atomAuthor  `xml:"author"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (s *syndication) atom() *atomDocument {
+	doc := &atomDocument{
+		ID:      s.FeedURL,
+		Title:   s.Title,
+		Updated: s.updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: s.HomeURL},
+			{Href: s.FeedURL, Rel: "self"},
+		},
+	}
+	for _, post := range s.Posts {
+		published := postTime(post).Format(time.RFC3339)
+		entry := atomEntry{
+			ID:        "urn:nuklai:tx:" + postGUID(post),
+			Title:     postTitle(post),
+			Updated:   published,
+			Published: published,
+			Author:    atomAuthor{Name: post.Address},
+			Content:   atomContent{Type: "text", Value: post.Content.Message},
+		}
+		if link := postLink(post); len(link) > 0 {
+			entry.Links = append(entry.Links, atomLink{Href: link})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
+
+type jsonFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func (s *syndication) jsonFeed() *jsonFeedDocument {
+	doc := &jsonFeedDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       s.Title,
+		HomePageURL: s.HomeURL,
+		FeedURL:     s.FeedURL,
+		Items:       []jsonFeedItem{},
+	}
+	for _, post := range s.Posts {
+		doc.Items = append(doc.Items, jsonFeedItem{
+			ID:            postGUID(post),
+			URL:           postLink(post),
+			Title:         postTitle(post),
+			ContentText:   post.Content.Message,
+			DatePublished: postTime(post).Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: post.Address}},
+		})
+	}
+	return doc
+}
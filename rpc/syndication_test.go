@@ -0,0 +1,91 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/nuklai/nuklai-feed/manager"
+)
+
+func TestPostLink(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://nukl.ai/post?id=1", "https://nukl.ai/post?id=1"},
+		{"http://nukl.ai", "http://nukl.ai"},
+		{"HTTPS://nukl.ai", "https://nukl.ai"},
+		{"", ""},
+		{"javascript:alert(1)", ""},
+		{"JavaScript:alert(1)", ""},
+		{"data:text/html,<script>alert(1)</script>", ""},
+		{"vbscript:msgbox(1)", ""},
+		{"file:///etc/passwd", ""},
+		{"ftp://nukl.ai", ""},
+		{"//nukl.ai", ""},
+		{"/relative", ""},
+		{"https://", ""},
+		{"https://nukl.ai/%zz", ""},
+		{" javascript:alert(1)", ""},
+	}
+	for _, tt := range tests {
+		post := &manager.FeedObject{Content: &manager.FeedContent{Message: "hi", URL: tt.url}}
+		if got := postLink(post); got != tt.want {
+			t.Errorf("postLink(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSyndicationDropsUnsafeLinks(t *testing.T) {
+	s := &syndication{
+		Title:   syndicationTitle,
+		HomeURL: "https://feed.nukl.ai/",
+		FeedURL: "https://feed.nukl.ai/feed.rss",
+		Posts: []*manager.FeedObject{
+			{TxID: ids.GenerateTestID(), Content: &manager.FeedContent{Message: "unsafe", URL: "javascript:alert(1)"}},
+			{TxID: ids.GenerateTestID(), Content: &manager.FeedContent{Message: "safe", URL: "https://nukl.ai"}},
+		},
+	}
+
+	rss := s.rss()
+	if link := rss.Channel.Items[0].Link; link != "" {
+		t.Fatalf("RSS item links to %q", link)
+	}
+	if link := rss.Channel.Items[1].Link; link != "https://nukl.ai" {
+		t.Fatalf("RSS item links to %q, want https://nukl.ai", link)
+	}
+
+	atom := s.atom()
+	if links := atom.Entries[0].Links; len(links) != 0 {
+		t.Fatalf("Atom entry links to %v", links)
+	}
+	if links := atom.Entries[1].Links; len(links) != 1 || links[0].Href != "https://nukl.ai" {
+		t.Fatalf("Atom entry links to %v, want https://nukl.ai", links)
+	}
+
+	feed := s.jsonFeed()
+	if url := feed.Items[0].URL; url != "" {
+		t.Fatalf("JSON Feed item links to %q", url)
+	}
+	if url := feed.Items[1].URL; url != "https://nukl.ai" {
+		t.Fatalf("JSON Feed item links to %q, want https://nukl.ai", url)
+	}
+
+	for name, marshal := range map[string]func(any) ([]byte, error){"xml": xml.Marshal, "json": json.Marshal} {
+		for _, doc := range []any{rss, atom, feed} {
+			raw, err := marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(raw), "javascript:") {
+				t.Fatalf("%s document %T contains the unsafe link: %s", name, doc, raw)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/nuklai/nuklai-feed/manager"
+)
+
+const (
+	WebSocketEndpoint = "/feed/ws"
+)
+
+// WebSocketClient subscribes to posts pushed by the WebSocketServer.
+type WebSocketClient struct {
+	uri string
+}
+
+// NewWebSocketClient creates a client for the feed server at [uri], which
+// uses the same http(s) address as NewJSONRPCClient.
+func NewWebSocketClient(uri string) *WebSocketClient {
+	uri = strings.TrimSuffix(uri, "/")
+	switch {
+	case strings.HasPrefix(uri, "https://"):
+		uri = "wss://" + strings.TrimPrefix(uri, "https://")
+	case strings.HasPrefix(uri, "http://"):
+		uri = "ws://" + strings.TrimPrefix(uri, "http://")
+	}
+	return &WebSocketClient{uri: uri + WebSocketEndpoint}
+}
+
+// FeedSubscription is an open subscription created by Subscribe.
+type FeedSubscription struct {
+	conn *websocket.Conn
+}
+
+// Subscribe opens a subscription that receives every new post matching
+// [filter].
+func (cli *WebSocketClient) Subscribe(ctx context.Context, filter manager.FeedFilter) (*FeedSubscription, error) {
+	query := url.Values{}
+	if len(filter.Address) > 0 {
+		query.Set("address", filter.Address)
+	}
+	if filter.MinFee > 0 {
+		query.Set("minFee", strconv.FormatUint(filter.MinFee, 10))
+	}
+	if len(filter.ChainID) > 0 {
+		query.Set("chainID", filter.ChainID)
+	}
+	uri := cli.uri
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &FeedSubscription{conn: conn}, nil
+}
+
+// Next blocks until the next post is received. The server closes the
+// subscription if the client falls too far behind, after which Next returns
+// an error and the client should resubscribe.
+func (s *FeedSubscription) Next() (*manager.FeedObject, error) {
+	var feed manager.FeedObject
+	if err := s.conn.ReadJSON(&feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// Close closes the subscription.
+func (s *FeedSubscription) Close() error {
+	return s.conn.Close()
+}
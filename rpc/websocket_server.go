@@ -0,0 +1,128 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/gorilla/websocket"
+	"github.com/nuklai/nuklai-feed/manager"
+	"github.com/nuklai/nuklaivm/consts"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 1024
+)
+
+// WebSocketServer pushes every new post to connected clients as a JSON
+// encoded FeedObject. Clients can filter posts with the address, minFee and
+// chainID query parameters.
+type WebSocketServer struct {
+	m        Manager
+	upgrader websocket.Upgrader
+}
+
+func NewWebSocketServer(m Manager) *WebSocketServer {
+	return &WebSocketServer{
+		m: m,
+		upgrader: websocket.Upgrader{
+			// The feed is public and read-only, so any origin may subscribe.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+}
+
+// parseFeedFilter reads a FeedFilter from the query parameters of a
+// subscription request.
+func parseFeedFilter(query url.Values) (*manager.FeedFilter, error) {
+	filter := &manager.FeedFilter{
+		Address: query.Get("address"),
+		ChainID: query.Get("chainID"),
+	}
+	if len(filter.Address) > 0 {
+		if _, err := codec.ParseAddressBech32(consts.HRP, filter.Address); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", filter.Address, err)
+		}
+	}
+	if len(filter.ChainID) > 0 {
+		if _, err := ids.FromString(filter.ChainID); err != nil {
+			return nil, fmt.Errorf("invalid chainID %q: %w", filter.ChainID, err)
+		}
+	}
+	if minFee := query.Get("minFee"); len(minFee) > 0 {
+		fee, err := strconv.ParseUint(minFee, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minFee %q: %w", minFee, err)
+		}
+		filter.MinFee = fee
+	}
+	return filter, nil
+}
+
+func (s *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFeedFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		return
+	}
+	defer conn.Close()
+
+	sub := s.m.Subscribe(*filter)
+	defer sub.Close()
+
+	// Clients only send control frames, so the read loop just keeps the
+	// connection alive and notices when the client goes away.
+	done := make(chan struct{})
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case feed, ok := <-sub.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind")
+				_ = conn.WriteMessage(websocket.CloseMessage, msg)
+				return
+			}
+			if err := conn.WriteJSON(feed); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}